@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Nombre maximal de livres traités par requête groupée
+const maxBatchSize = 500
+
+// Actions possibles d'une requête groupée
+const (
+	batchStatus   = "status"
+	batchFavorite = "favorite"
+	batchShelf    = "shelf"
+	batchUnshelf  = "unshelf"
+	batchDelete   = "delete"
+)
+
+var (
+	errBatchOwnership = errors.New("Some books do not exist or do not belong to you")
+	errBatchInvalid   = errors.New("Invalid status change")
+)
+
+type BatchRequest struct {
+	BookIDs  []string `json:"bookIds"`
+	Action   string   `json:"action"`   // status, favorite, shelf, unshelf ou delete
+	Status   string   `json:"status"`   // Pour l'action status
+	Favorite bool     `json:"favorite"` // Pour l'action favorite
+	ShelfID  string   `json:"shelfId"`  // Pour les actions shelf et unshelf
+}
+
+// BatchBooks applies one action to a list of books in a single transaction,
+// then refreshes the stats and checks the achievements once
+func BatchBooks(c *fiber.Ctx) error {
+	sugar.Info("Received a batch books request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request BatchRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	bookIDs := uniqueIDs(request.BookIDs)
+	if len(bookIDs) == 0 || len(bookIDs) > maxBatchSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Between 1 and 500 book IDs are required",
+		})
+	}
+	for _, id := range bookIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid book ID: " + id,
+			})
+		}
+	}
+
+	switch request.Action {
+	case batchStatus:
+		if !isValidStatus(request.Status) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid book status",
+			})
+		}
+	case batchShelf, batchUnshelf:
+		if request.ShelfID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Shelf ID is required",
+			})
+		}
+	case batchFavorite, batchDelete:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Action must be status, favorite, shelf, unshelf or delete",
+		})
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Tous les livres doivent appartenir à l'utilisateur, sinon rien n'est modifié
+		var books []models.Book
+		if err := tx.Where("id IN ? AND user_id = ?", bookIDs, userID).Find(&books).Error; err != nil {
+			return err
+		}
+		if len(books) != len(bookIDs) {
+			return errBatchOwnership
+		}
+
+		if err := applyBatchAction(tx, uiidStr, request, books, bookIDs); err != nil {
+			return err
+		}
+
+		// Stats recalculées une seule fois pour tout le lot
+		_, err := refreshUserStats(tx, userID)
+		return err
+	})
+	if errors.Is(err, errBatchOwnership) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, errBatchInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, errShelfNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		sugar.Errorw("Batch operation failed", "action", request.Action, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to apply batch operation",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	sugar.Infow("Batch operation applied", "userID", userID, "action", request.Action, "books", len(bookIDs))
+	return c.JSON(fiber.Map{
+		"message":  "Batch operation applied successfully",
+		"affected": len(bookIDs),
+	})
+}
+
+// applyBatchAction runs the requested action on books already checked for ownership
+func applyBatchAction(tx *gorm.DB, userID string, request BatchRequest, books []models.Book, bookIDs []string) error {
+	switch request.Action {
+	case batchStatus:
+		for _, book := range books {
+			if book.Status == request.Status {
+				continue
+			}
+			updated := book
+			updated.Status = request.Status
+			if err := normalizeReadingTracking(&updated, book.Status); err != nil {
+				return fmt.Errorf("%w for %q: %v", errBatchInvalid, book.Title, err)
+			}
+			updated.Version++
+			if err := tx.Save(&updated).Error; err != nil {
+				return err
+			}
+			if err := recordBookHistory(tx, models.HistoryUpdate, &book, updated); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case batchFavorite:
+		if err := tx.Model(&models.Book{}).Where("id IN ?", bookIDs).Updates(map[string]interface{}{
+			"favorite": request.Favorite,
+			"version":  gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			updated := book
+			updated.Favorite = request.Favorite
+			if err := recordBookHistory(tx, models.HistoryUpdate, &book, updated); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case batchShelf, batchUnshelf:
+		var shelf models.Shelf
+		if err := tx.First(&shelf, "id = ? AND user_id = ?", request.ShelfID, userID).Error; err != nil {
+			return errShelfNotFound
+		}
+		if request.Action == batchShelf {
+			return addBooksToShelf(tx, shelf.ID, bookIDs)
+		}
+		return tx.Where("shelf_id = ? AND book_id IN ?", shelf.ID, bookIDs).Delete(&models.ShelfBook{}).Error
+
+	case batchDelete:
+		if err := tx.Where("id IN ?", bookIDs).Delete(&models.Book{}).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			if err := recordBookHistory(tx, models.HistoryDelete, &book, book); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// uniqueIDs drops the empty and repeated IDs while keeping their order
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
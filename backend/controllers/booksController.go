@@ -4,13 +4,22 @@ import (
 	"booksrendezvous-backend/database"
 	"booksrendezvous-backend/models"
 	"booksrendezvous-backend/services"
+	"booksrendezvous-backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// GetBooks returns all books for the authenticated user
+// GetBooks returns the books of the authenticated user, filtered, sorted and paginated by the query parameters
 func GetBooks(c *fiber.Ctx) error {
 	sugar.Info("Received a Book request")
 
@@ -30,9 +39,54 @@ func GetBooks(c *fiber.Ctx) error {
 		})
 	}
 
+	var params BookQuery
+	if err := c.QueryParser(&params); err != nil {
+		sugar.Errorw("Failed to parse query parameters", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse query parameters",
+		})
+	}
+
+	if params.Limit < 0 || params.Offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit and offset cannot be negative",
+		})
+	}
+	if params.Limit > maxBooksPageSize {
+		params.Limit = maxBooksPageSize
+	}
+
+	query, err := applyBookFilters(database.DB.Model(&models.Book{}).Where("user_id = ?", userID), params)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	order, err := bookOrder(params)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// count matching books before pagination
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		sugar.Errorw("Failed to count books", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get books",
+		})
+	}
+
 	// get books from book table
+	page := query.Session(&gorm.Session{}).Order(order)
+	if params.Limit > 0 {
+		page = page.Limit(params.Limit).Offset(params.Offset)
+	}
+
 	var books []models.Book
-	if err := database.DB.Where("user_id = ?", userID).Find(&books).Error; err != nil {
+	if err := page.Find(&books).Error; err != nil {
 		sugar.Errorw("Failed to get books", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get books",
@@ -40,7 +94,10 @@ func GetBooks(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"books": books,
+		"books":  books,
+		"total":  total,
+		"limit":  params.Limit,
+		"offset": params.Offset,
 	})
 
 }
@@ -74,6 +131,43 @@ func CheckAuth(c *fiber.Ctx) (string, bool) {
 	return userID.String(), true
 }
 
+var (
+	errBookNotFound  = errors.New("Book not found")
+	errBookForbidden = errors.New("You are not authorized to access this book")
+)
+
+// findUserBook loads a book and checks that it belongs to the user
+func findUserBook(userID uuid.UUID, bookID string) (models.Book, error) {
+	var book models.Book
+	if err := database.DB.First(&book, "id = ?", bookID).Error; err != nil {
+		sugar.Errorw("Book not found", "bookID", bookID, "error", err)
+		return book, errBookNotFound
+	}
+
+	if book.UserID != userID.String() {
+		sugar.Errorw("Unauthorized book access attempt",
+			"userID", userID,
+			"bookUserID", book.UserID,
+		)
+		return book, errBookForbidden
+	}
+
+	return book, nil
+}
+
+// bookLookupError maps findUserBook errors to the matching HTTP response
+func bookLookupError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, errBookNotFound) {
+		status = fiber.StatusNotFound
+	} else if errors.Is(err, errBookForbidden) {
+		status = fiber.StatusForbidden
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
 type Book struct {
 	ID            string   `json:"id"`
 	GoogleBooksID string   `json:"googleBooksId"`
@@ -82,18 +176,61 @@ type Book struct {
 	Authors       []string `json:"authors"`
 	Description   string   `json:"description"`
 	ImageURL      string   `json:"imageUrl"`
-	Rating        float64  `json:"rating"`
+	Rating        *float64 `json:"rating"` // null ou 0 = non noté
 	Comment       string   `json:"comment"`
 	Favorite      bool     `json:"favorite"`
 	PageCount     int      `json:"pageCount"`
 	Genres        []string `json:"genres"`
 	PublishedDate string   `json:"publishedDate"`
+	Progress      int      `json:"progress"`
+	StartDate     string   `json:"startDate"`
+	EndDate       string   `json:"endDate"`
+	Abandoned     bool     `json:"abandoned"`
+	ISBN          string   `json:"isbn"` // Utilisé pour l'enrichissement des métadonnées
+	SeriesID      string   `json:"seriesId"`
+	SeriesName    string   `json:"seriesName"` // Série créée si elle n'existe pas, ignoré si seriesId est fourni
+	VolumeNumber  int      `json:"volumeNumber"`
+
+	// Édition et unité de progression
+	Format          string `json:"format"` // paperback, hardcover, ebook ou audiobook
+	Publisher       string `json:"publisher"`
+	Language        string `json:"language"`
+	DurationMinutes int    `json:"durationMinutes"`
+	Narrator        string `json:"narrator"`
+	ProgressUnit    string `json:"progressUnit"` // pages, percent ou minutes, selon le format par défaut
 }
 
 type AddBookRequest struct {
 	Book Book `json:"book"`
 }
 
+// bookFields lists the fields present in the book of a request, nil when they cannot be told apart, every field then counting as sent
+type bookFields map[string]json.RawMessage
+
+// sentBookFields reads the fields of the book sent in a JSON body, to tell an absent field from a zero value
+func sentBookFields(body []byte) bookFields {
+	var request struct {
+		Book bookFields `json:"book"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil
+	}
+	return request.Book
+}
+
+// has reports whether one of the fields was sent
+func (f bookFields) has(fields ...string) bool {
+	if f == nil {
+		return true
+	}
+	for _, field := range fields {
+		if _, ok := f[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func AddBook(c *fiber.Ctx) error {
 	sugar.Info("Received an Add Book request")
 
@@ -130,8 +267,13 @@ func AddBook(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if the Book contains all necessary fields
+	// Optionally complete the book from the metadata providers
 	book := request.Book
+	if c.QueryBool("enrich") {
+		enrichBookRequest(&book)
+	}
+
+	// Check if the Book contains all necessary fields
 	if book.Title == "" || book.ID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Book data is incomplete",
@@ -139,9 +281,18 @@ func AddBook(c *fiber.Ctx) error {
 	}
 
 	// Validate the rating field
-	if book.Rating < 0 || book.Rating > 5 {
+	rating, err := normalizeRating(book.Rating)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Rating must be between 0 and 5",
+			"error": err.Error(),
+		})
+	}
+
+	// Normalize the ISBN, the duplicate check is done once the book is mapped
+	isbn10, isbn13, err := normalizeOptionalISBN(book.ISBN)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ISBN",
 		})
 	}
 
@@ -154,27 +305,67 @@ func AddBook(c *fiber.Ctx) error {
 		Authors:       book.Authors,
 		Description:   book.Description,
 		ImageUrl:      book.ImageURL,
-		Rating:        int(book.Rating), // Cast the float64 rating into int
+		Rating:        rating,
 		Comment:       book.Comment,
 		UserID:        uiidStr, // Link the book to the user
 		Favorite:      book.Favorite,
 		PageCount:     book.PageCount,
 		Genres:        book.Genres,
 		PublishedDate: book.PublishedDate,
+		ISBN10:        isbn10,
+		ISBN13:        isbn13,
+
+		Format:          book.Format,
+		Publisher:       book.Publisher,
+		Language:        book.Language,
+		DurationMinutes: book.DurationMinutes,
+		Narrator:        book.Narrator,
+		ProgressUnit:    book.ProgressUnit,
+	}
+	if realbook.Status == "" {
+		realbook.Status = models.StatusToRead
+	}
+
+	// Same ISBN, Google Books ID or title and author: ?force=true adds it anyway, except for the ISBN
+	if existing, matchedOn, found := findDuplicateBook(userID, realbook); found {
+		if matchedOn == duplicateISBN || !c.QueryBool("force") {
+			return duplicateBookConflict(c, existing, matchedOn)
+		}
+	}
+
+	// Validate and apply reading progress tracking
+	if err := applyReadingTracking(&realbook, book, "", nil); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := applySeries(uiidStr, &realbook, book); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	// Save the book to the database
-	result := database.DB.Create(&realbook)
-	if result.Error != nil {
-		sugar.Errorw("Failed to save book to database", "error", result.Error)
+	// Save the book to the database, with its history and the user stats
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&realbook).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryCreate, nil, realbook); err != nil {
+			return err
+		}
+		return OnAddUpdateStats(tx, userID, realbook)
+	})
+	if isUniqueViolation(err) {
+		return duplicateBookResponse(c, userID, realbook)
+	}
+	if err != nil {
+		sugar.Errorw("Failed to save book to database", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save book to database",
 		})
 	}
 
-	//update user stats
-	OnAddUpdateStats(userID, realbook)
-
 	// Check if the user has unlocked any achievements
 	service := services.NewAchievementService(database.DB)
 
@@ -193,6 +384,135 @@ func AddBook(c *fiber.Ctx) error {
 	})
 }
 
+type ISBNBookRequest struct {
+	Status   string `json:"status"`
+	Favorite bool   `json:"favorite"`
+	Format   string `json:"format"` // paperback par défaut
+}
+
+// AddBookByISBN resolves an ISBN through the metadata providers and adds the book in one call
+func AddBookByISBN(c *fiber.Ctx) error {
+	sugar.Info("Received an Add Book by ISBN request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	// convert string to uuid type
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	isbn10, isbn13, err := utils.NormalizeISBN(c.Params("isbn"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid ISBN",
+		})
+	}
+
+	// The body is optional, the book defaults to the to-read status
+	var request ISBNBookRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			sugar.Errorw("Failed to parse request body", "error", err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to parse request body",
+			})
+		}
+	}
+
+	if existing, found := findBookByISBN(userID, isbn13); found {
+		return duplicateBookConflict(c, existing, duplicateISBN)
+	}
+
+	service := services.NewMetadataService(database.DB)
+	metadata, err := service.LookupISBN(isbn13)
+	if errors.Is(err, services.ErrMetadataNotFound) && isbn10 != "" {
+		metadata, err = service.LookupISBN(isbn10)
+	}
+	if errors.Is(err, services.ErrMetadataNotFound) || (err == nil && metadata.Title == "") {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No book found for this ISBN",
+		})
+	}
+	if err != nil {
+		sugar.Errorw("Metadata lookup failed", "isbn", isbn13, "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to fetch metadata",
+		})
+	}
+
+	book := models.Book{
+		ID:            uuid.New().String(),
+		UserID:        uiidStr,
+		GoogleBooksID: metadata.GoogleBooksID,
+		Status:        request.Status,
+		Title:         metadata.Title,
+		Authors:       metadata.Authors,
+		Description:   metadata.Description,
+		ImageUrl:      metadata.ImageURL,
+		Favorite:      request.Favorite,
+		PageCount:     metadata.PageCount,
+		Genres:        metadata.Genres,
+		PublishedDate: metadata.PublishedDate,
+		ISBN10:        isbn10,
+		ISBN13:        isbn13,
+		Format:        request.Format,
+		Publisher:     metadata.Publisher,
+		Language:      metadata.Language,
+	}
+	if book.Status == "" {
+		book.Status = models.StatusToRead
+	}
+	if book.Authors == nil {
+		book.Authors = []string{}
+	}
+	if existing, matchedOn, found := findDuplicateBook(userID, book); found && !c.QueryBool("force") {
+		return duplicateBookConflict(c, existing, matchedOn)
+	}
+	if metadata.SeriesName != "" {
+		if err := applySeries(uiidStr, &book, Book{SeriesName: metadata.SeriesName, VolumeNumber: metadata.VolumeNumber}); err != nil {
+			sugar.Warnw("Failed to link book to its series", "isbn", isbn13, "error", err)
+		}
+	}
+
+	if err := normalizeReadingTracking(&book, ""); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := saveNewBook(userID, &book); isUniqueViolation(err) {
+		return duplicateBookResponse(c, userID, book)
+	} else if err != nil {
+		sugar.Errorw("Failed to add book by ISBN", "isbn", isbn13, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save book to database",
+		})
+	}
+
+	// Check if the user has unlocked any achievements
+	achievements := services.NewAchievementService(database.DB)
+	if err := achievements.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Book added successfully",
+		"book":    book,
+	})
+}
+
 func DeleteBook(c *fiber.Ctx) error {
 	sugar.Info("Received a Delete Book request")
 
@@ -241,17 +561,23 @@ func DeleteBook(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete the book from the database
-	if err := database.DB.Delete(&book).Error; err != nil {
+	// Move the book to the trash, it can be restored until it is purged
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&book).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryDelete, &book, book); err != nil {
+			return err
+		}
+		return OnDeleteUpdateStats(tx, userID, book)
+	})
+	if err != nil {
 		sugar.Errorw("Failed to delete book from database", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete book from database",
 		})
 	}
 
-	//update user stats
-	OnDeleteUpdateStats(userID, book)
-
 	// Check if the user has unlocked any achievements
 	service := services.NewAchievementService(database.DB)
 
@@ -263,10 +589,10 @@ func DeleteBook(c *fiber.Ctx) error {
 		})
 	}
 
-	sugar.Infow("Book deleted successfully", "bookID", bookID)
+	sugar.Infow("Book moved to trash", "bookID", bookID)
 
 	return c.JSON(fiber.Map{
-		"message": "Book deleted successfully",
+		"message": "Book moved to trash",
 	})
 }
 
@@ -316,6 +642,7 @@ func UpdateBook(c *fiber.Ctx) error {
 
 	// Check if the Book contains all necessary fields
 	livre := request.Book
+	sent := sentBookFields(c.Body())
 	if livre.Title == "" || livre.ID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Book data is incomplete",
@@ -323,20 +650,13 @@ func UpdateBook(c *fiber.Ctx) error {
 	}
 
 	// Validate the rating field
-	if livre.Rating < 0 || livre.Rating > 5 {
+	rating, err := normalizeRating(livre.Rating)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Rating must be between 0 and 5",
+			"error": err.Error(),
 		})
 	}
 
-	// Map the request to the actual `models.Book`
-	reallivre := models.Book{
-		Status:   livre.Status,
-		Rating:   int(livre.Rating), // Cast the float64 rating into int
-		Comment:  livre.Comment,
-		Favorite: livre.Favorite,
-	}
-
 	// Find the existing book in the database
 	var book models.Book
 	if err := database.DB.First(&book, "id = ?", bookID).Error; err != nil {
@@ -357,7 +677,109 @@ func UpdateBook(c *fiber.Ctx) error {
 		})
 	}
 
-	OnChangeUpdateStats(userID, reallivre, book)
+	// Version attendue par le client, facultative, comme pour PATCH
+	expectedVersion, err := patchExpectedVersion(c, sent)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if expectedVersion != 0 && expectedVersion != book.Version {
+		return bookVersionConflict(c, book)
+	}
+
+	// Map the request onto a copy of the existing book
+	reallivre := book
+	if livre.Status != "" {
+		reallivre.Status = livre.Status
+	}
+	reallivre.Rating = rating
+	reallivre.Comment = livre.Comment
+	reallivre.Favorite = livre.Favorite
+	reallivre.Publisher = livre.Publisher
+	reallivre.Language = livre.Language
+	reallivre.Narrator = livre.Narrator
+	reallivre.DurationMinutes = livre.DurationMinutes
+	if livre.Format != "" {
+		reallivre.Format = livre.Format
+	}
+	if livre.ProgressUnit != "" {
+		reallivre.ProgressUnit = livre.ProgressUnit
+	} else if reallivre.Format != book.Format {
+		// Un changement de format sans unité explicite reprend l'unité par défaut du format
+		reallivre.ProgressUnit = defaultProgressUnit(reallivre.Format)
+	}
+	// Une progression absente ou inchangée est convertie dans la nouvelle unité, comme pour PATCH
+	if reallivre.ProgressUnit != book.ProgressUnit && (!sent.has("progress") || livre.Progress == book.Progress) {
+		livre.Progress = convertProgress(book.Progress, progressLimit(book), progressLimit(reallivre))
+		reallivre.Progress = livre.Progress
+	}
+
+	// Validate and apply reading progress tracking
+	if err := applyReadingTracking(&reallivre, livre, book.Status, sent); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// La série n'est modifiée que si la requête en parle, comme pour PATCH
+	if sent.has("seriesId", "seriesName", "volumeNumber") {
+		seriesRequest := Book{VolumeNumber: book.VolumeNumber}
+		if book.SeriesID != nil {
+			seriesRequest.SeriesID = *book.SeriesID
+		}
+		if sent.has("seriesId") || sent.has("seriesName") {
+			seriesRequest.SeriesID = livre.SeriesID
+			seriesRequest.SeriesName = livre.SeriesName
+		}
+		if sent.has("volumeNumber") {
+			seriesRequest.VolumeNumber = livre.VolumeNumber
+		}
+		if err := applySeries(uiidStr, &reallivre, seriesRequest); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	// Save the updated book before the stats, completed series are counted from the database.
+	// La mise à jour échoue si le livre a changé depuis sa lecture
+	reallivre.Version = book.Version + 1
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Book{}).
+			Where("id = ? AND version = ?", book.ID, book.Version).
+			Select("*").Omit("User", "Series", "CreatedAt").
+			Updates(&reallivre)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBookChanged
+		}
+		if err := recordBookHistory(tx, models.HistoryUpdate, &book, reallivre); err != nil {
+			return err
+		}
+		if err := OnChangeUpdateStats(tx, userID, reallivre, book); err != nil {
+			return err
+		}
+		if readingProgressed(book, reallivre) {
+			return OnReadingDayUpdateStats(tx, userID, time.Now())
+		}
+		return nil
+	})
+	if errors.Is(err, errBookChanged) {
+		current, err := findUserBook(userID, book.ID)
+		if err != nil {
+			return bookLookupError(c, err)
+		}
+		return bookVersionConflict(c, current)
+	}
+	if err != nil {
+		sugar.Errorw("Failed to update book in database", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update book in database",
+		})
+	}
 
 	// Check if the user has unlocked any achievements
 	service := services.NewAchievementService(database.DB)
@@ -370,22 +792,205 @@ func UpdateBook(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update the book with the new data
-	book.Comment = reallivre.Comment
-	book.Rating = reallivre.Rating
-	book.Status = reallivre.Status
-	book.Favorite = reallivre.Favorite
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.Itoa(reallivre.Version)))
+	return c.JSON(fiber.Map{
+		"message": "Book updated successfully",
+		"book":    reallivre,
+	})
+}
 
-	// Save the updated book to the database
-	if err := database.DB.Save(&book).Error; err != nil {
-		sugar.Errorw("Failed to update book in database", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update book in database",
-		})
+// parseBookDate accepte une date au format 2006-01-02 ou RFC3339, une chaîne vide donne nil
+func parseBookDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
 	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid date format")
+}
 
-	return c.JSON(fiber.Map{
-		"message": "Book updated successfully",
-		"book":    book,
+// normalizeRating validates a half-star rating, nil or 0 meaning unrated
+func normalizeRating(rating *float64) (*float64, error) {
+	if rating == nil || *rating == 0 {
+		return nil, nil
+	}
+	if *rating < 0.5 || *rating > 5 || math.Mod(*rating*2, 1) != 0 {
+		return nil, errors.New("Rating must be between 0.5 and 5 in half steps")
+	}
+	value := *rating
+	return &value, nil
+}
+
+// isValidStatus checks that the status is one of the supported reading statuses
+func isValidStatus(status string) bool {
+	switch status {
+	case models.StatusToRead, models.StatusReading, models.StatusFinished, models.StatusAbandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// applyReadingTracking copies progress, dates and abandoned flag from the request onto the book.
+// On an update, the fields missing from sent keep their stored value; a creation passes nil.
+func applyReadingTracking(book *models.Book, request Book, previousStatus string, sent bookFields) error {
+	if request.Abandoned {
+		book.Status = models.StatusAbandoned
+	}
+
+	startDate, err := parseBookDate(request.StartDate)
+	if err != nil {
+		return errors.New("Invalid start date")
+	}
+	endDate, err := parseBookDate(request.EndDate)
+	if err != nil {
+		return errors.New("Invalid end date")
+	}
+
+	if sent.has("progress") {
+		book.Progress = request.Progress
+	}
+	if sent.has("startDate") {
+		book.StartDate = startDate
+	}
+	if sent.has("endDate") {
+		book.EndDate = endDate
+	}
+
+	return normalizeReadingTracking(book, previousStatus)
+}
+
+// isValidFormat checks that the format is one of the supported book formats
+func isValidFormat(format string) bool {
+	switch format {
+	case models.FormatPaperback, models.FormatHardcover, models.FormatEbook, models.FormatAudiobook:
+		return true
+	default:
+		return false
+	}
+}
+
+// isValidProgressUnit checks that the progress unit is pages, percent or minutes
+func isValidProgressUnit(unit string) bool {
+	switch unit {
+	case models.ProgressPages, models.ProgressPercent, models.ProgressMinutes:
+		return true
+	default:
+		return false
+	}
+}
+
+// defaultProgressUnit returns minutes for audiobooks, percent for ebooks and pages otherwise
+func defaultProgressUnit(format string) string {
+	switch format {
+	case models.FormatAudiobook:
+		return models.ProgressMinutes
+	case models.FormatEbook:
+		return models.ProgressPercent
+	default:
+		return models.ProgressPages
+	}
+}
+
+// progressLimit returns the progress of a finished book in its progress unit, 0 when unknown
+func progressLimit(book models.Book) int {
+	switch book.ProgressUnit {
+	case models.ProgressPercent:
+		return 100
+	case models.ProgressMinutes:
+		return book.DurationMinutes
+	default:
+		return book.PageCount
+	}
+}
+
+// normalizeReadingTracking fills automatic dates on status transitions and validates format, progress and dates
+func normalizeReadingTracking(book *models.Book, previousStatus string) error {
+	if book.Status != previousStatus && !isValidStatus(book.Status) {
+		return errors.New("Invalid book status")
+	}
+
+	if book.Format == "" {
+		book.Format = models.FormatPaperback
+	}
+	if !isValidFormat(book.Format) {
+		return errors.New("Format must be paperback, hardcover, ebook or audiobook")
+	}
+	if book.ProgressUnit == "" {
+		book.ProgressUnit = defaultProgressUnit(book.Format)
+	}
+	if !isValidProgressUnit(book.ProgressUnit) {
+		return errors.New("Progress unit must be pages, percent or minutes")
+	}
+	if book.DurationMinutes < 0 {
+		return errors.New("Duration cannot be negative")
+	}
+
+	// Dates automatiques lors d'un changement de statut
+	if book.Status != previousStatus {
+		now := time.Now()
+		switch book.Status {
+		case models.StatusReading:
+			if book.StartDate == nil {
+				book.StartDate = &now
+			}
+		case models.StatusFinished:
+			if book.EndDate == nil {
+				book.EndDate = &now
+			}
+			if limit := progressLimit(*book); limit > 0 {
+				book.Progress = limit
+			}
+		}
+	}
+
+	if book.Progress < 0 {
+		return errors.New("Progress cannot be negative")
+	}
+	if limit := progressLimit(*book); limit > 0 && book.Progress > limit {
+		return fmt.Errorf("Progress cannot exceed %d %s", limit, book.ProgressUnit)
+	}
+	if book.StartDate != nil && book.EndDate != nil && book.EndDate.Before(*book.StartDate) {
+		return errors.New("End date cannot be before start date")
+	}
+
+	return nil
+}
+
+// saveNewBook stores a new book with its history and the user stats
+func saveNewBook(userID uuid.UUID, book *models.Book) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryCreate, nil, *book); err != nil {
+			return err
+		}
+		return OnAddUpdateStats(tx, userID, *book)
 	})
+	if err != nil {
+		return fmt.Errorf("failed to save book: %w", err)
+	}
+	return nil
+}
+
+// normalizeOptionalISBN returns the ISBN-10 and ISBN-13 forms of an ISBN, or empty strings when none is given
+func normalizeOptionalISBN(value string) (string, string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", "", nil
+	}
+	return utils.NormalizeISBN(value)
+}
+
+// findBookByISBN looks up a book of the user by its ISBN-13
+func findBookByISBN(userID uuid.UUID, isbn13 string) (models.Book, bool) {
+	var book models.Book
+	if isbn13 == "" {
+		return book, false
+	}
+	err := database.DB.Where("user_id = ? AND isbn13 = ?", userID, isbn13).First(&book).Error
+	return book, err == nil
 }
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"booksrendezvous-backend/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Critères de détection d'un doublon
+const (
+	duplicateISBN        = "isbn"
+	duplicateGoogleBooks = "googleBooksId"
+	duplicateTitle       = "title"
+)
+
+// Code Postgres d'une violation d'index unique
+const uniqueViolation = "23505"
+
+// Avancement d'un statut de lecture, le plus avancé l'emporte lors d'une fusion
+var statusRank = map[string]int{
+	models.StatusToRead:    0,
+	models.StatusAbandoned: 1,
+	models.StatusReading:   2,
+	models.StatusFinished:  3,
+}
+
+type MergeBooksRequest struct {
+	SourceID string `json:"sourceId"` // Livre fusionné puis mis à la corbeille
+}
+
+// findDuplicateBook looks for a book of the user with the same ISBN, Google Books ID,
+// or normalized title and author, and returns the criterion that matched
+func findDuplicateBook(userID uuid.UUID, book models.Book) (models.Book, string, bool) {
+	if existing, found := findBookByISBN(userID, book.ISBN13); found {
+		return existing, duplicateISBN, true
+	}
+
+	var existing models.Book
+	if book.GoogleBooksID != "" {
+		if err := database.DB.Where("user_id = ? AND google_books_id = ?", userID, book.GoogleBooksID).
+			First(&existing).Error; err == nil {
+			return existing, duplicateGoogleBooks, true
+		}
+	}
+
+	// Le mot le plus long du titre restreint les candidats en SQL, la comparaison exacte se fait ensuite
+	title := utils.NormalizeTitle(book.Title)
+	if title == "" {
+		return existing, "", false
+	}
+	var candidates []models.Book
+	if err := database.DB.Where("user_id = ?", userID).
+		Where(plainTitleSQL+" LIKE ?", "%"+utils.TitleKeyword(book.Title)+"%").
+		Find(&candidates).Error; err != nil {
+		sugar.Errorw("Failed to look for duplicate books", "userID", userID, "error", err)
+		return existing, "", false
+	}
+	for _, candidate := range candidates {
+		if utils.NormalizeTitle(candidate.Title) == title && sameAuthor(candidate.Authors, book.Authors) {
+			return candidate, duplicateTitle, true
+		}
+	}
+	return existing, "", false
+}
+
+// plainTitleSQL lowercases the title column and drops its accents as utils.NormalizeTitle does,
+// punctuation aside, so that it contains the keyword of every title normalizing the same way
+var plainTitleSQL = func() string {
+	var from, to strings.Builder
+	var ligatures []string
+	pairs := utils.AccentPairs()
+	for i := 0; i < len(pairs); i += 2 {
+		accent, plain := pairs[i], pairs[i+1]
+		upper := strings.ToUpper(accent)
+		if len(plain) > 1 || len([]rune(upper)) > 1 {
+			ligatures = append(ligatures, accent, plain)
+			if upper != accent {
+				ligatures = append(ligatures, upper, plain)
+			}
+			continue
+		}
+		// lower() ne connaît pas les majuscules accentuées avec une locale C
+		from.WriteString(accent + upper)
+		to.WriteString(plain + plain)
+	}
+
+	expression := fmt.Sprintf("translate(lower(title), '%s', '%s')", from.String(), to.String())
+	for i := 0; i < len(ligatures); i += 2 {
+		expression = fmt.Sprintf("replace(%s, '%s', '%s')", expression, ligatures[i], ligatures[i+1])
+	}
+	return expression
+}()
+
+// sameAuthor reports whether both lists share an author, or are both empty
+func sameAuthor(a []string, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	for _, left := range a {
+		for _, right := range b {
+			if name := utils.NormalizeAuthor(left); name != "" && name == utils.NormalizeAuthor(right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// duplicateBookConflict answers 409 with the book already in the library
+func duplicateBookConflict(c *fiber.Ctx, existing models.Book, matchedOn string) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":     "Book already in library",
+		"book":      existing,
+		"matchedOn": matchedOn,
+	})
+}
+
+// duplicateBookResponse answers 409 once the unique ISBN index rejected a book added concurrently
+func duplicateBookResponse(c *fiber.Ctx, userID uuid.UUID, book models.Book) error {
+	existing, matchedOn, found := findDuplicateBook(userID, book)
+	if !found {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Book already in library",
+		})
+	}
+	return duplicateBookConflict(c, existing, matchedOn)
+}
+
+// isUniqueViolation reports whether an insert hit a unique index, such as the ISBN of a book added concurrently
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolation
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// MergeBooks merges the source book into the target book: notes, sessions, loans, history and shelves are moved,
+// the reading data are combined, then the source book is moved to the trash and the stats recomputed
+func MergeBooks(c *fiber.Ctx) error {
+	sugar.Info("Received a Merge Books request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request MergeBooksRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	target, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+	if request.SourceID == "" || request.SourceID == target.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A different source book ID is required",
+		})
+	}
+	source, err := findUserBook(userID, request.SourceID)
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Un livre n'a qu'un prêt en cours, les deux ne peuvent pas l'être
+	var openLoans int64
+	database.DB.Model(&models.Loan{}).Where("book_id IN ? AND returned_at IS NULL", []string{target.ID, source.ID}).Count(&openLoans)
+	if openLoans > 1 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Both books are currently lent, return one of them first",
+		})
+	}
+
+	merged := mergeBookFields(target, source)
+	if limit := progressLimit(merged); limit > 0 && merged.Progress > limit {
+		merged.Progress = limit
+	}
+	if err := normalizeReadingTracking(&merged, target.Status); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	merged.Version++
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.BookNote{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ReadingSession{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Loan{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.BookHistory{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// Étagères : les liens déjà présents pour la cible sont abandonnés
+		if err := tx.Where("book_id = ? AND shelf_id IN (?)", source.ID,
+			tx.Model(&models.ShelfBook{}).Select("shelf_id").Where("book_id = ?", target.ID)).
+			Delete(&models.ShelfBook{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShelfBook{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// Mis à la corbeille avant l'enregistrement de la cible, qui peut reprendre son ISBN ;
+		// une fusion par erreur se rattrape en restaurant la source
+		if err := tx.Delete(&source).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryMerge, &source, source); err != nil {
+			return err
+		}
+		if err := tx.Save(&merged).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryMerge, &target, merged); err != nil {
+			return err
+		}
+		_, err := refreshUserStats(tx, userID)
+		return err
+	})
+	if err != nil {
+		sugar.Errorw("Failed to merge books", "targetID", target.ID, "sourceID", source.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to merge books",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	sugar.Infow("Books merged successfully", "targetID", target.ID, "sourceID", source.ID)
+	return c.JSON(fiber.Map{
+		"message": "Books merged successfully",
+		"book":    merged,
+	})
+}
+
+// mergeBookFields completes the target with the source: empty fields are filled,
+// the most advanced status and progress are kept, and the reading dates widened
+func mergeBookFields(target models.Book, source models.Book) models.Book {
+	merged := target
+
+	if statusRank[source.Status] > statusRank[merged.Status] {
+		merged.Status = source.Status
+	}
+	if source.ProgressUnit == merged.ProgressUnit && source.Progress > merged.Progress {
+		merged.Progress = source.Progress
+	}
+	if merged.Rating == nil {
+		merged.Rating = source.Rating
+	}
+	merged.Favorite = merged.Favorite || source.Favorite
+
+	if source.StartDate != nil && (merged.StartDate == nil || source.StartDate.Before(*merged.StartDate)) {
+		merged.StartDate = source.StartDate
+	}
+	if source.EndDate != nil && (merged.EndDate == nil || source.EndDate.After(*merged.EndDate)) {
+		merged.EndDate = source.EndDate
+	}
+
+	if source.Comment != "" && !strings.Contains(merged.Comment, source.Comment) {
+		if merged.Comment != "" {
+			merged.Comment += "\n\n"
+		}
+		merged.Comment += source.Comment
+	}
+
+	// Métadonnées manquantes reprises de la source
+	if merged.Description == "" {
+		merged.Description = source.Description
+	}
+	if merged.ImageUrl == "" {
+		merged.ImageUrl = source.ImageUrl
+	}
+	if merged.GoogleBooksID == "" {
+		merged.GoogleBooksID = source.GoogleBooksID
+	}
+	if merged.PageCount == 0 {
+		merged.PageCount = source.PageCount
+	}
+	if merged.PublishedDate == "" {
+		merged.PublishedDate = source.PublishedDate
+	}
+	if merged.ISBN13 == "" {
+		merged.ISBN10, merged.ISBN13 = source.ISBN10, source.ISBN13
+	}
+	if len(merged.Authors) == 0 {
+		merged.Authors = source.Authors
+	}
+	if merged.Publisher == "" {
+		merged.Publisher = source.Publisher
+	}
+	if merged.Language == "" {
+		merged.Language = source.Language
+	}
+	if merged.Format == source.Format {
+		if merged.DurationMinutes == 0 {
+			merged.DurationMinutes = source.DurationMinutes
+		}
+		if merged.Narrator == "" {
+			merged.Narrator = source.Narrator
+		}
+	}
+	if merged.SeriesID == nil {
+		merged.SeriesID, merged.VolumeNumber = source.SeriesID, source.VolumeNumber
+	}
+
+	genres := append([]string{}, merged.Genres...)
+	for _, genre := range source.Genres {
+		if !containsFold(genres, genre) {
+			genres = append(genres, genre)
+		}
+	}
+	merged.Genres = genres
+
+	return merged
+}
+
+// containsFold reports whether the list contains the value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// errBookChanged signale un livre modifié depuis sa lecture
+var errBookChanged = errors.New("Book changed since it was read")
+
+// PatchBook applies a JSON merge patch (RFC 7396) to a book.
+// The expected version can be given in the If-Match header or in the "version" field,
+// a stale version returns 409 with the current book.
+func PatchBook(c *fiber.Ctx) error {
+	sugar.Info("Received a Patch Book request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &patch); err != nil || patch == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Request body must be a JSON object",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Version attendue par le client, facultative
+	expectedVersion, err := patchExpectedVersion(c, patch)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if expectedVersion != 0 && expectedVersion != book.Version {
+		return bookVersionConflict(c, book)
+	}
+
+	patched := book
+	if err := applyBookPatch(uiidStr, &patched, patch); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if patched.ISBN13 != "" && patched.ISBN13 != book.ISBN13 {
+		if existing, found := findBookByISBN(userID, patched.ISBN13); found && existing.ID != book.ID {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Book already in library",
+				"book":  existing,
+			})
+		}
+	}
+
+	// La mise à jour échoue si le livre a changé depuis sa lecture
+	patched.Version = book.Version + 1
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Book{}).
+			Where("id = ? AND version = ?", book.ID, book.Version).
+			Select("*").Omit("User", "Series", "CreatedAt").
+			Updates(&patched)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errBookChanged
+		}
+		if err := recordBookHistory(tx, models.HistoryUpdate, &book, patched); err != nil {
+			return err
+		}
+		if err := OnChangeUpdateStats(tx, userID, patched, book); err != nil {
+			return err
+		}
+		if readingProgressed(book, patched) {
+			return OnReadingDayUpdateStats(tx, userID, time.Now())
+		}
+		return nil
+	})
+	if errors.Is(err, errBookChanged) {
+		current, err := findUserBook(userID, book.ID)
+		if err != nil {
+			return bookLookupError(c, err)
+		}
+		return bookVersionConflict(c, current)
+	}
+	if isUniqueViolation(err) {
+		return duplicateBookResponse(c, userID, patched)
+	}
+	if err != nil {
+		sugar.Errorw("Failed to patch book", "bookID", book.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update book in database",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.Itoa(patched.Version)))
+	return c.JSON(fiber.Map{
+		"message": "Book updated successfully",
+		"book":    patched,
+	})
+}
+
+func bookVersionConflict(c *fiber.Ctx, current models.Book) error {
+	c.Set(fiber.HeaderETag, strconv.Quote(strconv.Itoa(current.Version)))
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "Book was modified since it was read",
+		"book":  current,
+	})
+}
+
+// patchExpectedVersion reads the expected version from If-Match or from the patch or PUT body, 0 when none is given
+func patchExpectedVersion(c *fiber.Ctx, patch map[string]json.RawMessage) (int, error) {
+	if raw, ok := patch["version"]; ok {
+		delete(patch, "version")
+		var version int
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, errors.New("Invalid version")
+		}
+		return version, nil
+	}
+
+	header := strings.TrimPrefix(strings.TrimSpace(c.Get(fiber.HeaderIfMatch)), "W/")
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil {
+		return 0, errors.New("Invalid If-Match header")
+	}
+	return version, nil
+}
+
+// convertProgress carries the read fraction over to another progress unit, 0 when a limit is unknown
+func convertProgress(progress int, fromLimit int, toLimit int) int {
+	if fromLimit <= 0 || toLimit <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(progress) * float64(toLimit) / float64(fromLimit)))
+}
+
+// applyBookPatch copies the fields present in the patch onto the book, null resetting a field
+func applyBookPatch(userID string, book *models.Book, patch map[string]json.RawMessage) error {
+	previousStatus := book.Status
+	previousUnit, previousLimit := book.ProgressUnit, progressLimit(*book)
+	var seriesPatch *Book
+
+	for field, raw := range patch {
+		isNull := string(raw) == "null"
+		var err error
+
+		switch field {
+		case "title":
+			err = json.Unmarshal(raw, &book.Title)
+			book.Title = strings.TrimSpace(book.Title)
+		case "authors":
+			var authors []string
+			err = json.Unmarshal(raw, &authors)
+			book.Authors = pq.StringArray(trimAll(authors))
+		case "description":
+			book.Description = ""
+			err = json.Unmarshal(raw, &book.Description)
+		case "imageUrl":
+			book.ImageUrl = ""
+			err = json.Unmarshal(raw, &book.ImageUrl)
+		case "googleBooksId":
+			book.GoogleBooksID = ""
+			err = json.Unmarshal(raw, &book.GoogleBooksID)
+		case "comment":
+			book.Comment = ""
+			err = json.Unmarshal(raw, &book.Comment)
+		case "favorite":
+			book.Favorite = false
+			err = json.Unmarshal(raw, &book.Favorite)
+		case "status":
+			err = json.Unmarshal(raw, &book.Status)
+		case "rating":
+			var rating *float64
+			if err = json.Unmarshal(raw, &rating); err == nil {
+				if book.Rating, err = normalizeRating(rating); err != nil {
+					return err
+				}
+			}
+		case "pageCount":
+			book.PageCount = 0
+			err = json.Unmarshal(raw, &book.PageCount)
+		case "genres":
+			var genres []string
+			err = json.Unmarshal(raw, &genres)
+			book.Genres = pq.StringArray(genres)
+		case "publishedDate":
+			book.PublishedDate = ""
+			err = json.Unmarshal(raw, &book.PublishedDate)
+		case "progress":
+			book.Progress = 0
+			err = json.Unmarshal(raw, &book.Progress)
+		case "progressUnit":
+			book.ProgressUnit = ""
+			err = json.Unmarshal(raw, &book.ProgressUnit)
+		case "format":
+			book.Format = ""
+			err = json.Unmarshal(raw, &book.Format)
+		case "publisher":
+			book.Publisher = ""
+			err = json.Unmarshal(raw, &book.Publisher)
+		case "language":
+			book.Language = ""
+			err = json.Unmarshal(raw, &book.Language)
+		case "narrator":
+			book.Narrator = ""
+			err = json.Unmarshal(raw, &book.Narrator)
+		case "durationMinutes":
+			book.DurationMinutes = 0
+			err = json.Unmarshal(raw, &book.DurationMinutes)
+		case "startDate", "endDate":
+			var value string
+			if err = json.Unmarshal(raw, &value); err == nil {
+				date, parseErr := parseBookDate(value)
+				if parseErr != nil {
+					return fmt.Errorf("Invalid %s", field)
+				}
+				if field == "startDate" {
+					book.StartDate = date
+				} else {
+					book.EndDate = date
+				}
+			}
+		case "isbn":
+			var isbn string
+			if err = json.Unmarshal(raw, &isbn); err == nil {
+				book.ISBN10, book.ISBN13, err = normalizeOptionalISBN(isbn)
+				if err != nil {
+					return errors.New("Invalid ISBN")
+				}
+			}
+		case "seriesId", "seriesName", "volumeNumber":
+			if seriesPatch == nil {
+				seriesPatch = &Book{VolumeNumber: book.VolumeNumber}
+				if book.SeriesID != nil {
+					seriesPatch.SeriesID = *book.SeriesID
+				}
+			}
+			switch field {
+			case "seriesId":
+				seriesPatch.SeriesID = ""
+				err = json.Unmarshal(raw, &seriesPatch.SeriesID)
+			case "seriesName":
+				err = json.Unmarshal(raw, &seriesPatch.SeriesName)
+			default:
+				seriesPatch.VolumeNumber = 0
+				err = json.Unmarshal(raw, &seriesPatch.VolumeNumber)
+			}
+		default:
+			return fmt.Errorf("Unknown or read-only field: %s", field)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Invalid value for %s", field)
+		}
+		if isNull && (field == "title" || field == "status") {
+			return fmt.Errorf("%s cannot be null", field)
+		}
+	}
+
+	if book.Title == "" {
+		return errors.New("Title cannot be empty")
+	}
+	if book.Authors == nil {
+		book.Authors = pq.StringArray{}
+	}
+	if book.PageCount < 0 {
+		return errors.New("Page count cannot be negative")
+	}
+
+	// Un changement de format sans unité explicite reprend l'unité par défaut du format
+	_, hasUnit := patch["progressUnit"]
+	if _, hasFormat := patch["format"]; hasFormat && !hasUnit {
+		book.ProgressUnit = defaultProgressUnit(book.Format)
+	}
+	// La progression est convertie dans la nouvelle unité si elle n'est pas fournie
+	if _, hasProgress := patch["progress"]; !hasProgress && book.ProgressUnit != previousUnit {
+		book.Progress = convertProgress(book.Progress, previousLimit, progressLimit(*book))
+	}
+
+	if seriesPatch != nil {
+		// Un nom de série sans identifiant remplace la série actuelle
+		if _, hasID := patch["seriesId"]; !hasID {
+			if _, hasName := patch["seriesName"]; hasName {
+				seriesPatch.SeriesID = ""
+			}
+		}
+		if err := applySeries(userID, book, *seriesPatch); err != nil {
+			return err
+		}
+	}
+
+	return normalizeReadingTracking(book, previousStatus)
+}
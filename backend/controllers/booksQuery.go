@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxBooksPageSize = 100
+
+// BookQuery holds the filters, sort order and pagination accepted by GET /api/books
+type BookQuery struct {
+	Status    string `query:"status"`    // Un ou plusieurs statuts séparés par des virgules
+	Favorite  string `query:"favorite"`  // true / false
+	Genre     string `query:"genre"`     // Genre exact (insensible à la casse)
+	Author    string `query:"author"`    // Partie du nom d'un auteur
+	MinRating string `query:"minRating"` // Note minimale incluse
+	MaxRating string `query:"maxRating"` // Note maximale incluse
+	Year      string `query:"year"`      // Année de publication
+	Search    string `query:"q"`         // Recherche libre titre / auteurs
+	Shelf     string `query:"shelf"`     // Identifiant d'une étagère
+	Sort      string `query:"sort"`      // Champ de tri, préfixe "-" pour l'ordre décroissant
+	Limit     int    `query:"limit"`     // 0 = pas de pagination
+	Offset    int    `query:"offset"`
+}
+
+// bookSortColumns maps the public sort keys to SQL columns
+var bookSortColumns = map[string]string{
+	"title":     "title",
+	"rating":    "rating",
+	"added":     "created_at",
+	"published": "published_date",
+	"pages":     "page_count",
+	"progress":  "progress",
+	"started":   "start_date",
+	"finished":  "end_date",
+}
+
+// applyBookFilters adds the WHERE clauses described by the query
+func applyBookFilters(query *gorm.DB, params BookQuery) (*gorm.DB, error) {
+	if params.Status != "" {
+		statuses := strings.Split(params.Status, ",")
+		for _, status := range statuses {
+			if !isValidStatus(strings.TrimSpace(status)) {
+				return nil, errors.New("Invalid status filter")
+			}
+		}
+		query = query.Where("status IN ?", trimAll(statuses))
+	}
+
+	if params.Favorite != "" {
+		favorite, err := strconv.ParseBool(params.Favorite)
+		if err != nil {
+			return nil, errors.New("Invalid favorite filter")
+		}
+		query = query.Where("favorite = ?", favorite)
+	}
+
+	if params.Genre != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM unnest(genres) AS g WHERE g ILIKE ?)", escapeLike(params.Genre))
+	}
+
+	if params.Author != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM unnest(authors) AS a WHERE a ILIKE ?)", "%"+escapeLike(params.Author)+"%")
+	}
+
+	if params.MinRating != "" {
+		rating, err := strconv.ParseFloat(params.MinRating, 64)
+		if err != nil {
+			return nil, errors.New("Invalid minRating filter")
+		}
+		query = query.Where("rating >= ?", rating)
+	}
+
+	if params.MaxRating != "" {
+		rating, err := strconv.ParseFloat(params.MaxRating, 64)
+		if err != nil {
+			return nil, errors.New("Invalid maxRating filter")
+		}
+		query = query.Where("rating <= ?", rating)
+	}
+
+	if params.Year != "" {
+		if _, err := strconv.Atoi(params.Year); err != nil || len(params.Year) != 4 {
+			return nil, errors.New("Invalid year filter")
+		}
+		query = query.Where("LEFT(published_date, 4) = ?", params.Year)
+	}
+
+	if params.Search != "" {
+		pattern := "%" + escapeLike(params.Search) + "%"
+		query = query.Where("(title ILIKE ? OR array_to_string(authors, ' ') ILIKE ?)", pattern, pattern)
+	}
+
+	if params.Shelf != "" {
+		if _, err := uuid.Parse(params.Shelf); err != nil {
+			return nil, errors.New("Invalid shelf filter")
+		}
+		query = query.Where("id IN (SELECT book_id FROM shelf_books WHERE shelf_id = ?)", params.Shelf)
+	}
+
+	return query, nil
+}
+
+// bookOrder converts the sort parameter into an ORDER BY clause
+func bookOrder(params BookQuery) (string, error) {
+	sort := params.Sort
+	if sort == "" {
+		return "title ASC, id ASC", nil
+	}
+
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+		sort = strings.TrimPrefix(sort, "-")
+	}
+
+	column, ok := bookSortColumns[sort]
+	if sort == "position" {
+		// L'ordre d'une étagère n'a de sens qu'avec le filtre shelf
+		shelfID, err := uuid.Parse(params.Shelf)
+		if err != nil {
+			return "", errors.New("Sorting by position requires a shelf filter")
+		}
+		column, ok = "(SELECT position FROM shelf_books WHERE shelf_books.book_id = books.id AND shelf_books.shelf_id = '"+shelfID.String()+"')", true
+	}
+	if !ok {
+		return "", errors.New("Invalid sort parameter")
+	}
+
+	// L'id garantit un ordre stable entre deux pages
+	return column + " " + direction + " NULLS LAST, id ASC", nil
+}
+
+// escapeLike escapes the LIKE wildcards of a user supplied string
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func trimAll(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strings.TrimSpace(value))
+	}
+	return result
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Nombre de livres lus en base à chaque lot lors d'un export
+const exportBatchSize = 200
+
+// Colonnes de l'export CSV natif
+var exportCSVHeader = []string{
+	"id", "title", "authors", "status", "rating", "favorite", "comment", "genres",
+	"page_count", "progress", "progress_unit", "published_date", "start_date", "end_date",
+	"google_books_id", "isbn10", "isbn13", "format", "publisher", "language",
+	"duration_minutes", "narrator", "created_at",
+}
+
+type exportedAchievement struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	UnlockedAt  time.Time `json:"unlockedAt"`
+}
+
+// ExportLibrary streams the user's library as CSV, JSON or Goodreads-compatible CSV
+func ExportLibrary(c *fiber.Ctx) error {
+	sugar.Info("Received a library export request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" && format != "goodreads" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format must be json, csv or goodreads",
+		})
+	}
+
+	includeStats, includeAchievements := false, false
+	if include := c.Query("include"); include != "" {
+		for _, part := range strings.Split(include, ",") {
+			switch strings.TrimSpace(part) {
+			case "stats":
+				includeStats = true
+			case "achievements":
+				includeAchievements = true
+			default:
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Include must be stats and/or achievements",
+				})
+			}
+		}
+	}
+	if format != "json" && (includeStats || includeAchievements) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Stats and achievements can only be included in json exports",
+		})
+	}
+
+	extension := "csv"
+	if format == "json" {
+		extension = "json"
+	}
+	c.Attachment(fmt.Sprintf("booksrendezvous-%s-%s.%s", format, time.Now().Format("20060102"), extension))
+
+	// Le corps est écrit au fil de l'eau, les erreurs ne peuvent plus qu'être journalisées
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		var err error
+		switch format {
+		case "csv":
+			err = writeBooksCSV(w, userID)
+		case "goodreads":
+			err = writeGoodreadsCSV(w, userID)
+		default:
+			err = writeLibraryJSON(w, userID, includeStats, includeAchievements)
+		}
+		if err != nil {
+			sugar.Errorw("Library export failed", "userID", userID, "format", format, "error", err)
+		}
+		w.Flush()
+	})
+
+	sugar.Infow("Library export started", "userID", userID, "format", format)
+	return nil
+}
+
+// forEachBook iterates over the user's books in batches to keep memory bounded
+func forEachBook(userID uuid.UUID, fn func(models.Book) error) error {
+	var batch []models.Book
+	return database.DB.Where("user_id = ?", userID).FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, book := range batch {
+			if err := fn(book); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func writeBooksCSV(w *bufio.Writer, userID uuid.UUID) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportCSVHeader); err != nil {
+		return err
+	}
+
+	err := forEachBook(userID, func(book models.Book) error {
+		return writer.Write([]string{
+			book.ID,
+			book.Title,
+			strings.Join(book.Authors, "; "),
+			book.Status,
+			formatExportRating(book.Rating),
+			strconv.FormatBool(book.Favorite),
+			book.Comment,
+			strings.Join(book.Genres, "; "),
+			strconv.Itoa(book.PageCount),
+			strconv.Itoa(book.Progress),
+			book.ProgressUnit,
+			book.PublishedDate,
+			formatExportDate(book.StartDate),
+			formatExportDate(book.EndDate),
+			book.GoogleBooksID,
+			book.ISBN10,
+			book.ISBN13,
+			book.Format,
+			book.Publisher,
+			book.Language,
+			strconv.Itoa(book.DurationMinutes),
+			book.Narrator,
+			formatExportDate(&book.CreatedAt),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeGoodreadsCSV(w *bufio.Writer, userID uuid.UUID) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(services.GoodreadsHeader); err != nil {
+		return err
+	}
+
+	err := forEachBook(userID, func(book models.Book) error {
+		return writer.Write(services.GoodreadsRecord(book))
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeLibraryJSON(w *bufio.Writer, userID uuid.UUID, includeStats, includeAchievements bool) error {
+	fmt.Fprintf(w, `{"exportedAt":%q,"books":[`, time.Now().Format(time.RFC3339))
+
+	first := true
+	err := forEachBook(userID, func(book models.Book) error {
+		data, err := json.Marshal(book)
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.WriteByte(']')
+
+	if includeStats {
+		data, err := json.Marshal(newStatsResponse(loadUserStats(userID)))
+		if err != nil {
+			return err
+		}
+		w.WriteString(`,"stats":`)
+		w.Write(data)
+	}
+
+	if includeAchievements {
+		var achievements []exportedAchievement
+		if err := database.DB.Table("user_achievements").
+			Select("achievements.name, achievements.description, achievements.category, user_achievements.unlocked_at").
+			Joins("JOIN achievements ON achievements.id = user_achievements.achievement_id").
+			Where("user_achievements.user_id = ? AND user_achievements.unlocked_at > ?", userID, time.Time{}).
+			Order("user_achievements.unlocked_at").
+			Scan(&achievements).Error; err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(achievements)
+		if err != nil {
+			return err
+		}
+		w.WriteString(`,"achievements":`)
+		w.Write(data)
+	}
+
+	_, err = w.WriteString("}")
+	return err
+}
+
+func formatExportRating(rating *float64) string {
+	if rating == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*rating, 'f', 1, 64)
+}
+
+func formatExportDate(date *time.Time) string {
+	if date == nil || date.IsZero() {
+		return ""
+	}
+	return date.Format(time.RFC3339)
+}
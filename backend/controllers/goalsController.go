@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Avancement d'un objectif par rapport au rythme attendu
+const (
+	goalCompleted = "completed"
+	goalAhead     = "ahead"  // En avance ou à l'heure sur le rythme régulier
+	goalBehind    = "behind" // En retard sur le rythme régulier
+)
+
+var errGoalNotFound = errors.New("Reading goal not found")
+
+type GoalRequest struct {
+	Metric string `json:"metric"` // books, pages ou minutes
+	Period string `json:"period"` // week, month ou year
+	Target int    `json:"target"`
+}
+
+type goalProgress struct {
+	models.ReadingGoal
+	PeriodStart         string  `json:"periodStart"`
+	PeriodEnd           string  `json:"periodEnd"` // Dernier jour de la période
+	Current             int     `json:"current"`
+	Percent             float64 `json:"percent"`
+	Expected            int     `json:"expected"`            // Atteint à ce jour au rythme régulier
+	Projected           int     `json:"projected"`           // Fin de période au rythme actuel
+	ProjectedCompletion *string `json:"projectedCompletion"` // Nil sans progression
+	Status              string  `json:"status"`
+}
+
+// GetGoals returns the reading goals of the user with their progress over the current period
+func GetGoals(c *fiber.Ctx) error {
+	sugar.Info("Received a reading goals request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var goals []models.ReadingGoal
+	if err := database.DB.Where("user_id = ?", uiidStr).Order("period, metric").Find(&goals).Error; err != nil {
+		sugar.Errorw("Failed to get reading goals", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get reading goals",
+		})
+	}
+
+	now := time.Now()
+	result := make([]goalProgress, 0, len(goals))
+	for _, goal := range goals {
+		progress, err := measureGoal(goal, now)
+		if err != nil {
+			sugar.Errorw("Failed to measure reading goal", "goalID", goal.ID, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get reading goals",
+			})
+		}
+		result = append(result, progress)
+	}
+
+	return c.JSON(fiber.Map{
+		"goals": result,
+	})
+}
+
+// CreateGoal sets a books, pages or minutes goal per week, month or year
+func CreateGoal(c *fiber.Ctx) error {
+	sugar.Info("Received a create reading goal request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request GoalRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	if err = validateGoal(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.ReadingGoal{}).Where("user_id = ? AND metric = ? AND period = ?", uiidStr, request.Metric, request.Period).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A goal already exists for this metric and period",
+		})
+	}
+
+	goal := models.ReadingGoal{
+		UserID: uiidStr,
+		Metric: request.Metric,
+		Period: request.Period,
+		Target: request.Target,
+	}
+	if err := database.DB.Create(&goal).Error; err != nil {
+		sugar.Errorw("Failed to create reading goal", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create reading goal",
+		})
+	}
+
+	return goalResponse(c, userID, goal, "Reading goal created successfully")
+}
+
+// UpdateGoal changes the target of a reading goal
+func UpdateGoal(c *fiber.Ctx) error {
+	sugar.Info("Received an update reading goal request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request GoalRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+	if request.Target <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Target must be positive",
+		})
+	}
+
+	goal, err := findUserGoal(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	goal.Target = request.Target
+	if err := database.DB.Save(&goal).Error; err != nil {
+		sugar.Errorw("Failed to update reading goal", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update reading goal",
+		})
+	}
+
+	return goalResponse(c, userID, goal, "Reading goal updated successfully")
+}
+
+// DeleteGoal removes a reading goal, the achievements it unlocked are kept
+func DeleteGoal(c *fiber.Ctx) error {
+	sugar.Info("Received a delete reading goal request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	goal, err := findUserGoal(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Delete(&goal).Error; err != nil {
+		sugar.Errorw("Failed to delete reading goal", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete reading goal",
+		})
+	}
+
+	// Le total des objectifs atteints est recompté
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Reading goal deleted successfully",
+	})
+}
+
+// goalResponse checks the achievements, a goal can already be reached when it is set, then returns its progress
+func goalResponse(c *fiber.Ctx, userID uuid.UUID, goal models.ReadingGoal, message string) error {
+	// Les succès sont vérifiés à partir des stats, créées au besoin
+	loadUserStats(userID)
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(userID.String()); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	// Relu pour les périodes atteintes enregistrées par la vérification
+	if err := database.DB.First(&goal, "id = ?", goal.ID).Error; err != nil {
+		sugar.Errorw("Failed to reload reading goal", "goalID", goal.ID, "error", err)
+	}
+	progress, err := measureGoal(goal, time.Now())
+	if err != nil {
+		sugar.Errorw("Failed to measure reading goal", "goalID", goal.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to measure reading goal",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+		"goal":    progress,
+	})
+}
+
+func validateGoal(request GoalRequest) error {
+	if request.Metric != models.GoalBooks && request.Metric != models.GoalPages && request.Metric != models.GoalMinutes {
+		return errors.New("Metric must be books, pages or minutes")
+	}
+	if request.Period != models.GoalWeek && request.Period != models.GoalMonth && request.Period != models.GoalYear {
+		return errors.New("Period must be week, month or year")
+	}
+	if request.Target <= 0 {
+		return errors.New("Target must be positive")
+	}
+	return nil
+}
+
+func findUserGoal(userID string, goalID string) (models.ReadingGoal, error) {
+	var goal models.ReadingGoal
+	if _, err := uuid.Parse(goalID); err != nil {
+		return goal, errGoalNotFound
+	}
+	if err := database.DB.First(&goal, "id = ? AND user_id = ?", goalID, userID).Error; err != nil {
+		sugar.Errorw("Reading goal not found", "goalID", goalID, "error", err)
+		return goal, errGoalNotFound
+	}
+	return goal, nil
+}
+
+// measureGoal computes the progress of a goal over its current period and projects it at the current pace
+func measureGoal(goal models.ReadingGoal, now time.Time) (goalProgress, error) {
+	start, end := services.GoalPeriod(goal.Period, now)
+	current, err := services.GoalProgress(database.DB, goal, start, end)
+	if err != nil {
+		return goalProgress{}, err
+	}
+
+	// Part écoulée de la période
+	elapsed := now.Sub(start)
+	fraction := elapsed.Hours() / end.Sub(start).Hours()
+
+	progress := goalProgress{
+		ReadingGoal: goal,
+		PeriodStart: start.Format("2006-01-02"),
+		PeriodEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Current:     current,
+		Percent:     math.Round(float64(current)/float64(goal.Target)*1000) / 10,
+		Expected:    int(math.Ceil(float64(goal.Target) * fraction)),
+		Projected:   current,
+	}
+	if fraction > 0 {
+		progress.Projected = int(math.Round(float64(current) / fraction))
+	}
+
+	switch {
+	case current >= goal.Target:
+		progress.Status = goalCompleted
+	case current >= progress.Expected:
+		progress.Status = goalAhead
+	default:
+		progress.Status = goalBehind
+	}
+
+	// Date à laquelle la cible serait atteinte en gardant le même rythme
+	if current > 0 && current < goal.Target && elapsed > 0 {
+		remaining := time.Duration(float64(elapsed) * float64(goal.Target-current) / float64(current))
+		date := now.Add(remaining).Format("2006-01-02")
+		progress.ProjectedCompletion = &date
+	}
+	return progress, nil
+}
+
+// countCompletedGoals returns the total of the periods in which the goals of the user were reached
+func countCompletedGoals(db *gorm.DB, userID uuid.UUID) int {
+	var total int64
+	if err := db.Model(&models.ReadingGoal{}).Select("COALESCE(SUM(completed_periods), 0)").
+		Where("user_id = ?", userID).Scan(&total).Error; err != nil {
+		sugar.Errorw("Failed to count completed goals", "userID", userID, "error", err)
+		return 0
+	}
+	return int(total)
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetBookHistory returns the changes made to a book, most recent first
+func GetBookHistory(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Book History request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	limit, offset := c.QueryInt("limit", maxBooksPageSize), c.QueryInt("offset", 0)
+	if limit < 0 || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit and offset cannot be negative",
+		})
+	}
+	if limit == 0 || limit > maxBooksPageSize {
+		limit = maxBooksPageSize
+	}
+
+	history := []models.BookHistory{}
+	if err := database.DB.Where("book_id = ?", book.ID).
+		Order("created_at DESC, id").
+		Limit(limit).Offset(offset).
+		Find(&history).Error; err != nil {
+		sugar.Errorw("Failed to get book history", "bookID", book.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get book history",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"history": history,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// recordBookHistory stores the fields that changed between two states of a book.
+// before is nil for a creation; an update or a session that changes nothing is not recorded.
+func recordBookHistory(tx *gorm.DB, action string, before *models.Book, after models.Book) error {
+	changes := diffBooks(before, after)
+	if (action == models.HistoryUpdate || action == models.HistorySession) && len(changes) == 0 {
+		return nil
+	}
+
+	return tx.Create(&models.BookHistory{
+		BookID:  after.ID,
+		UserID:  after.UserID,
+		Action:  action,
+		Changes: changes,
+	}).Error
+}
+
+// diffBooks lists the tracked fields whose value differs, all the non-empty fields when before is nil
+func diffBooks(before *models.Book, after models.Book) models.BookChanges {
+	changes := models.BookChanges{}
+	newValues := bookSnapshot(after)
+
+	if before == nil {
+		for field, value := range newValues {
+			if !isEmptyHistoryValue(value) {
+				changes[field] = models.FieldChange{New: value}
+			}
+		}
+		return changes
+	}
+
+	oldValues := bookSnapshot(*before)
+	for field, value := range newValues {
+		if !reflect.DeepEqual(oldValues[field], value) {
+			changes[field] = models.FieldChange{Old: oldValues[field], New: value}
+		}
+	}
+	return changes
+}
+
+// bookSnapshot returns the tracked fields of a book, keyed by their JSON name
+func bookSnapshot(book models.Book) map[string]interface{} {
+	var rating, seriesID interface{}
+	if book.Rating != nil {
+		rating = *book.Rating
+	}
+	if book.SeriesID != nil {
+		seriesID = *book.SeriesID
+	}
+
+	return map[string]interface{}{
+		"title":           book.Title,
+		"authors":         historyList(book.Authors),
+		"description":     book.Description,
+		"imageUrl":        book.ImageUrl,
+		"googleBooksId":   book.GoogleBooksID,
+		"comment":         book.Comment,
+		"favorite":        book.Favorite,
+		"status":          book.Status,
+		"rating":          rating,
+		"pageCount":       book.PageCount,
+		"genres":          historyList(book.Genres),
+		"publishedDate":   book.PublishedDate,
+		"isbn10":          book.ISBN10,
+		"isbn13":          book.ISBN13,
+		"progress":        book.Progress,
+		"progressUnit":    book.ProgressUnit,
+		"format":          book.Format,
+		"publisher":       book.Publisher,
+		"language":        book.Language,
+		"durationMinutes": book.DurationMinutes,
+		"narrator":        book.Narrator,
+		"startDate":       formatExportDate(book.StartDate),
+		"endDate":         formatExportDate(book.EndDate),
+		"seriesId":        seriesID,
+		"volumeNumber":    book.VolumeNumber,
+	}
+}
+
+// historyList copies a list so that nil and empty lists compare as equal
+func historyList(values []string) []string {
+	list := make([]string, len(values))
+	copy(list, values)
+	return list
+}
+
+func isEmptyHistoryValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case int:
+		return v == 0
+	case bool:
+		return !v
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
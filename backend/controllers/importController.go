@@ -0,0 +1,489 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"booksrendezvous-backend/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Fréquence de mise à jour de la progression d'un import
+const importProgressStep = 25
+
+// Seuils de ressemblance pour rattacher une note Kindle à un livre existant
+const (
+	kindleTitleSimilarity  = 0.85
+	kindleAuthorSimilarity = 0.8
+)
+
+// ImportGoodreads accepts a Goodreads CSV export and imports it in the background
+func ImportGoodreads(c *fiber.Ctx) error {
+	sugar.Info("Received a Goodreads import request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		sugar.Errorw("Missing import file", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A Goodreads CSV file is required",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		sugar.Errorw("Failed to open import file", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read import file",
+		})
+	}
+	defer file.Close()
+
+	// Le fichier est analysé tout de suite pour rejeter un CSV invalide
+	entries, err := services.ParseGoodreadsCSV(file)
+	if err != nil {
+		sugar.Errorw("Invalid Goodreads CSV", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid Goodreads CSV: " + err.Error(),
+		})
+	}
+
+	job := models.ImportJob{
+		UserID: uiidStr,
+		Source: "goodreads",
+		Status: models.ImportPending,
+		Total:  len(entries),
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		sugar.Errorw("Failed to create import job", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create import job",
+		})
+	}
+
+	go runGoodreadsImport(job, userID, entries)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Import started",
+		"job":     job,
+	})
+}
+
+// ImportKindle accepts a Kindle "My Clippings.txt" file and imports its highlights and notes in the background
+func ImportKindle(c *fiber.Ctx) error {
+	sugar.Info("Received a Kindle clippings import request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		sugar.Errorw("Missing import file", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A My Clippings.txt file is required",
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		sugar.Errorw("Failed to open import file", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read import file",
+		})
+	}
+	defer file.Close()
+
+	clippings, err := services.ParseKindleClippings(file)
+	if err != nil {
+		sugar.Errorw("Invalid Kindle clippings file", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid Kindle clippings file: " + err.Error(),
+		})
+	}
+
+	job := models.ImportJob{
+		UserID: uiidStr,
+		Source: "kindle",
+		Status: models.ImportPending,
+		Total:  len(clippings),
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		sugar.Errorw("Failed to create import job", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create import job",
+		})
+	}
+
+	go runKindleImport(job, userID, clippings)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Import started",
+		"job":     job,
+	})
+}
+
+// GetImportJob returns the progress of an import job
+func GetImportJob(c *fiber.Ctx) error {
+	sugar.Info("Received an import job status request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var job models.ImportJob
+	if err := database.DB.First(&job, "id = ? AND user_id = ?", c.Params("id"), uiidStr).Error; err != nil {
+		sugar.Errorw("Import job not found", "jobID", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import job not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"job": job,
+	})
+}
+
+// runGoodreadsImport creates the books of a Goodreads export, then refreshes stats and achievements once
+func runGoodreadsImport(job models.ImportJob, userID uuid.UUID, entries []services.GoodreadsEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			sugar.Errorw("Goodreads import panicked", "jobID", job.ID, "panic", r)
+			finishImportJob(&job, fmt.Errorf("unexpected error: %v", r))
+		}
+	}()
+
+	database.DB.Model(&job).Update("status", models.ImportRunning)
+	job.Status = models.ImportRunning
+
+	// Livres déjà présents pour éviter les doublons
+	var existing []models.Book
+	if err := database.DB.Select("title", "authors", "isbn13").Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+		finishImportJob(&job, err)
+		return
+	}
+	known := make(map[string]bool, len(existing))
+	for _, book := range existing {
+		known[importKey(book.Title, book.Authors)] = true
+		if book.ISBN13 != "" {
+			known["isbn:"+book.ISBN13] = true
+		}
+	}
+
+	for i, entry := range entries {
+		book := goodreadsBook(userID, entry)
+		key := importKey(book.Title, book.Authors)
+		isbnKey := "isbn:" + book.ISBN13
+		if known[key] || (book.ISBN13 != "" && known[isbnKey]) {
+			job.Skipped++
+		} else if err := createImportedBook(book); err != nil {
+			sugar.Errorw("Failed to import Goodreads row", "jobID", job.ID, "title", entry.Title, "error", err)
+			job.Failed++
+		} else {
+			known[key] = true
+			if book.ISBN13 != "" {
+				known[isbnKey] = true
+			}
+			job.Imported++
+		}
+		job.Processed = i + 1
+
+		if job.Processed%importProgressStep == 0 {
+			saveImportProgress(&job)
+		}
+	}
+
+	// Stats et succès recalculés une seule fois en fin d'import
+	if _, err := RefreshUserStats(userID); err != nil {
+		finishImportJob(&job, err)
+		return
+	}
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(userID.String()); err != nil {
+		finishImportJob(&job, err)
+		return
+	}
+
+	finishImportJob(&job, nil)
+	sugar.Infow("Goodreads import finished", "jobID", job.ID, "imported", job.Imported, "skipped", job.Skipped, "failed", job.Failed)
+}
+
+// createImportedBook stores an imported book with its history, the stats being refreshed at the end of the import
+func createImportedBook(book *models.Book) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return recordBookHistory(tx, models.HistoryCreate, nil, *book)
+	})
+}
+
+// runKindleImport attaches each clipping as a note to the matching book, creating the missing books.
+// Notes already imported are recognized by their fingerprint and skipped.
+func runKindleImport(job models.ImportJob, userID uuid.UUID, clippings []services.KindleClipping) {
+	defer func() {
+		if r := recover(); r != nil {
+			sugar.Errorw("Kindle import panicked", "jobID", job.ID, "panic", r)
+			finishImportJob(&job, fmt.Errorf("unexpected error: %v", r))
+		}
+	}()
+
+	database.DB.Model(&job).Update("status", models.ImportRunning)
+	job.Status = models.ImportRunning
+
+	var books []models.Book
+	if err := database.DB.Select("id", "title", "authors").Where("user_id = ?", userID).Find(&books).Error; err != nil {
+		finishImportJob(&job, err)
+		return
+	}
+
+	var fingerprints []string
+	if err := database.DB.Model(&models.BookNote{}).Where("user_id = ? AND fingerprint <> ''", userID).Pluck("fingerprint", &fingerprints).Error; err != nil {
+		finishImportJob(&job, err)
+		return
+	}
+	known := make(map[string]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		known[fingerprint] = true
+	}
+
+	// Un même titre/auteur n'est recherché qu'une fois
+	matched := make(map[string]string)
+	for i, clipping := range clippings {
+		if i > 0 && i%importProgressStep == 0 {
+			saveImportProgress(&job)
+		}
+		job.Processed = i + 1
+
+		fingerprint := clipping.Fingerprint()
+		if known[fingerprint] {
+			job.Skipped++
+			continue
+		}
+
+		groupKey := importKey(clipping.Title, []string{clipping.Author})
+		bookID, ok := matched[groupKey]
+		if !ok {
+			if book := matchKindleBook(books, clipping.Title, clipping.Author); book != nil {
+				bookID = book.ID
+			} else {
+				book := kindleBook(userID, clipping)
+				if err := createImportedBook(book); err != nil {
+					sugar.Errorw("Failed to create book for Kindle clipping", "jobID", job.ID, "title", clipping.Title, "error", err)
+					job.Failed++
+					continue
+				}
+				books = append(books, *book)
+				bookID = book.ID
+				job.CreatedBooks++
+			}
+			matched[groupKey] = bookID
+		}
+
+		note := models.BookNote{
+			UserID:      userID.String(),
+			BookID:      bookID,
+			Type:        clipping.Type,
+			Body:        clipping.Body,
+			Page:        clipping.Page,
+			Location:    clipping.Location,
+			Fingerprint: fingerprint,
+		}
+		if clipping.AddedAt != nil {
+			note.CreatedAt = *clipping.AddedAt
+		}
+		if err := database.DB.Create(&note).Error; err != nil {
+			sugar.Errorw("Failed to import Kindle clipping", "jobID", job.ID, "title", clipping.Title, "error", err)
+			job.Failed++
+			continue
+		}
+		known[fingerprint] = true
+		job.Imported++
+	}
+
+	// Les livres créés changent les stats, recalculées une seule fois
+	if job.CreatedBooks > 0 {
+		if _, err := RefreshUserStats(userID); err != nil {
+			finishImportJob(&job, err)
+			return
+		}
+		service := services.NewAchievementService(database.DB)
+		if err := service.CheckAchievements(userID.String()); err != nil {
+			finishImportJob(&job, err)
+			return
+		}
+	}
+
+	finishImportJob(&job, nil)
+	sugar.Infow("Kindle import finished", "jobID", job.ID, "imported", job.Imported, "createdBooks", job.CreatedBooks, "skipped", job.Skipped, "failed", job.Failed)
+}
+
+// matchKindleBook finds the book closest to a Kindle title and author, or nil when none is close enough
+func matchKindleBook(books []models.Book, title string, author string) *models.Book {
+	normalizedTitle := utils.NormalizeTitle(title)
+	normalizedAuthor := utils.NormalizeAuthor(author)
+
+	var best *models.Book
+	bestScore := 0.0
+	for i := range books {
+		score := utils.Similarity(normalizedTitle, utils.NormalizeTitle(books[i].Title))
+		if score < kindleTitleSimilarity {
+			continue
+		}
+
+		// L'auteur n'est comparé que s'il est connu des deux côtés
+		if normalizedAuthor != "" && len(books[i].Authors) > 0 {
+			authorScore := 0.0
+			for _, bookAuthor := range books[i].Authors {
+				if s := utils.Similarity(normalizedAuthor, utils.NormalizeAuthor(bookAuthor)); s > authorScore {
+					authorScore = s
+				}
+			}
+			if authorScore < kindleAuthorSimilarity {
+				continue
+			}
+		}
+
+		if score > bestScore {
+			best, bestScore = &books[i], score
+		}
+	}
+	return best
+}
+
+// kindleBook creates the book of a clipping that matches nothing in the library
+func kindleBook(userID uuid.UUID, clipping services.KindleClipping) *models.Book {
+	book := &models.Book{
+		ID:      uuid.New().String(),
+		UserID:  userID.String(),
+		Status:  models.StatusToRead,
+		Title:   clipping.Title,
+		Authors: []string{},
+
+		// Les notes Kindle viennent d'une liseuse
+		Format:       models.FormatEbook,
+		ProgressUnit: models.ProgressPercent,
+	}
+	for _, author := range strings.Split(clipping.Author, ";") {
+		if author = strings.TrimSpace(author); author != "" {
+			book.Authors = append(book.Authors, author)
+		}
+	}
+	return book
+}
+
+// goodreadsBook maps a Goodreads row to a book owned by the user
+func goodreadsBook(userID uuid.UUID, entry services.GoodreadsEntry) *models.Book {
+	book := &models.Book{
+		ID:            uuid.New().String(),
+		UserID:        userID.String(),
+		Status:        entry.Status(),
+		Title:         entry.Title,
+		Authors:       entry.Authors,
+		Comment:       entry.Review,
+		PageCount:     entry.PageCount,
+		PublishedDate: entry.PublishedYear,
+		EndDate:       entry.DateRead,
+		Publisher:     entry.Publisher,
+		Format:        entry.Format(),
+	}
+	book.ProgressUnit = defaultProgressUnit(book.Format)
+	if book.Authors == nil {
+		book.Authors = []string{}
+	}
+	// Les ISBN invalides de l'export sont ignorés
+	for _, isbn := range []string{entry.ISBN13, entry.ISBN} {
+		if isbn10, isbn13, err := utils.NormalizeISBN(isbn); err == nil {
+			book.ISBN10, book.ISBN13 = isbn10, isbn13
+			break
+		}
+	}
+	// 0 signifie "non noté" chez Goodreads
+	if entry.Rating >= 1 && entry.Rating <= 5 {
+		rating := float64(entry.Rating)
+		book.Rating = &rating
+	}
+	if book.Status == models.StatusFinished {
+		book.Progress = progressLimit(*book)
+	}
+	if entry.DateAdded != nil {
+		book.CreatedAt = *entry.DateAdded
+	}
+	return book
+}
+
+// importKey builds the deduplication key of a book from its title and first author
+func importKey(title string, authors []string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	if len(authors) > 0 {
+		key += "|" + strings.ToLower(strings.TrimSpace(authors[0]))
+	}
+	return key
+}
+
+func saveImportProgress(job *models.ImportJob) {
+	database.DB.Model(job).Updates(map[string]interface{}{
+		"processed":     job.Processed,
+		"imported":      job.Imported,
+		"created_books": job.CreatedBooks,
+		"skipped":       job.Skipped,
+		"failed":        job.Failed,
+	})
+}
+
+// finishImportJob stores the final counters and status of a job
+func finishImportJob(job *models.ImportJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.ImportDone
+	if err != nil {
+		sugar.Errorw("Import job failed", "jobID", job.ID, "error", err)
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+	}
+
+	database.DB.Model(job).Updates(map[string]interface{}{
+		"status":        job.Status,
+		"processed":     job.Processed,
+		"imported":      job.Imported,
+		"created_books": job.CreatedBooks,
+		"skipped":       job.Skipped,
+		"failed":        job.Failed,
+		"error":         job.Error,
+		"finished_at":   job.FinishedAt,
+	})
+}
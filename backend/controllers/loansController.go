@@ -0,0 +1,510 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Fréquence de la recherche des prêts en retard
+const loanReminderCheckInterval = 24 * time.Hour
+
+// Rappels envoyés au plus par prêteur à chaque recherche, les suivants attendent la prochaine
+const maxLoanRemindersPerLender = 5
+
+type LoanRequest struct {
+	BorrowerName  string `json:"borrowerName"`
+	BorrowerEmail string `json:"borrowerEmail"` // Propose le prêt au compte de l'emprunteur s'il est inscrit
+	LentAt        string `json:"lentAt"`        // Aujourd'hui par défaut
+	DueAt         string `json:"dueAt"`
+	Note          string `json:"note"`
+	SendReminders bool   `json:"sendReminders"` // Nécessite l'email de l'emprunteur
+}
+
+type ReturnLoanRequest struct {
+	ReturnedAt string `json:"returnedAt"` // Aujourd'hui par défaut
+}
+
+type loanWithBook struct {
+	models.Loan
+	BookTitle  string `json:"bookTitle"`
+	LenderName string `json:"lenderName,omitempty"`
+	Overdue    bool   `json:"overdue"`
+}
+
+// LendBook records that a physical book of the user was lent to someone
+func LendBook(c *fiber.Ctx) error {
+	sugar.Info("Received a Lend Book request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+	if book.Format == models.FormatEbook || book.Format == models.FormatAudiobook {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only physical books can be lent",
+		})
+	}
+
+	var request LoanRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	loan := models.Loan{
+		UserID:        uiidStr,
+		BookID:        book.ID,
+		BorrowerName:  strings.TrimSpace(request.BorrowerName),
+		BorrowerEmail: strings.ToLower(strings.TrimSpace(request.BorrowerEmail)),
+		Note:          request.Note,
+		SendReminders: request.SendReminders,
+	}
+
+	if loan.BorrowerEmail != "" {
+		if !isEmail(loan.BorrowerEmail) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid borrower email",
+			})
+		}
+		// Un emprunteur inscrit se voit proposer le prêt, la réponse ne révèle pas s'il a un compte
+		borrower, err := getUserByEmail(loan.BorrowerEmail)
+		if err != nil {
+			sugar.Errorw("Failed to look up borrower", "error", err)
+		} else if borrower != nil && borrower.ID != uiidStr {
+			loan.BorrowerUserID = &borrower.ID
+		}
+	}
+	if loan.BorrowerName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Borrower name is required",
+		})
+	}
+	if loan.SendReminders && loan.BorrowerEmail == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A borrower email is required to send reminders",
+		})
+	}
+
+	lentAt, err := parseBookDate(request.LentAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid lent date",
+		})
+	}
+	if lentAt == nil {
+		now := time.Now()
+		lentAt = &now
+	}
+	loan.LentAt = *lentAt
+	if loan.DueAt, err = parseBookDate(request.DueAt); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid due date",
+		})
+	}
+	if loan.DueAt != nil && loan.DueAt.Before(loan.LentAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Due date cannot be before lent date",
+		})
+	}
+
+	// Un livre ne peut être prêté qu'à une personne à la fois
+	var current models.Loan
+	if err := database.DB.Where("book_id = ? AND returned_at IS NULL", book.ID).First(&current).Error; err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Book is already lent",
+			"loan":  current,
+		})
+	}
+
+	if err := database.DB.Create(&loan).Error; err != nil {
+		sugar.Errorw("Failed to save loan", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save loan",
+		})
+	}
+
+	sugar.Infow("Book lent successfully", "bookID", book.ID, "loanID", loan.ID)
+	return c.JSON(fiber.Map{
+		"message": "Book lent successfully",
+		"loan":    newLoanWithBook(loan, book.Title, ""),
+	})
+}
+
+// ReturnLoan marks a lent book as returned
+func ReturnLoan(c *fiber.Ctx) error {
+	sugar.Info("Received a Return Loan request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request ReturnLoanRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			sugar.Errorw("Failed to parse request body", "error", err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to parse request body",
+			})
+		}
+	}
+
+	var loan models.Loan
+	if err := database.DB.Preload("Book").First(&loan, "id = ? AND user_id = ?", c.Params("id"), uiidStr).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Loan not found",
+		})
+	}
+	if loan.ReturnedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Loan already returned",
+			"loan":  loan,
+		})
+	}
+
+	returnedAt, err := parseBookDate(request.ReturnedAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid return date",
+		})
+	}
+	if returnedAt == nil {
+		now := time.Now()
+		returnedAt = &now
+	}
+	if returnedAt.Format("2006-01-02") < loan.LentAt.Format("2006-01-02") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Return date cannot be before lent date",
+		})
+	}
+
+	loan.ReturnedAt = returnedAt
+	if err := database.DB.Model(&loan).Update("returned_at", loan.ReturnedAt).Error; err != nil {
+		sugar.Errorw("Failed to return loan", "loanID", loan.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to return loan",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Book returned successfully",
+		"loan":    newLoanWithBook(loan, loan.Book.Title, ""),
+	})
+}
+
+// GetLoans lists the loans of the user, the books currently lent out by default.
+// The status query parameter accepts open, overdue, returned or all.
+func GetLoans(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Loans request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	query := database.DB.Preload("Book").Where("user_id = ?", uiidStr).Where(activeBookIDs("book_id"))
+	today := time.Now().Format("2006-01-02")
+	switch c.Query("status", "open") {
+	case "open":
+		query = query.Where("returned_at IS NULL")
+	case "overdue":
+		query = query.Where("returned_at IS NULL AND due_at < ?", today)
+	case "returned":
+		query = query.Where("returned_at IS NOT NULL")
+	case "all":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Status must be open, overdue, returned or all",
+		})
+	}
+
+	var loans []models.Loan
+	if err := query.Order("returned_at DESC NULLS FIRST, due_at NULLS LAST, lent_at").Find(&loans).Error; err != nil {
+		sugar.Errorw("Failed to get loans", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get loans",
+		})
+	}
+
+	response := make([]loanWithBook, 0, len(loans))
+	for _, loan := range loans {
+		response = append(response, newLoanWithBook(loan, loan.Book.Title, ""))
+	}
+	return c.JSON(fiber.Map{
+		"loans": response,
+	})
+}
+
+// GetBookLoans returns the lending history of a book
+func GetBookLoans(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Book Loans request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	var loans []models.Loan
+	if err := database.DB.Where("book_id = ?", book.ID).Order("lent_at DESC").Find(&loans).Error; err != nil {
+		sugar.Errorw("Failed to get book loans", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get book loans",
+		})
+	}
+
+	response := make([]loanWithBook, 0, len(loans))
+	for _, loan := range loans {
+		response = append(response, newLoanWithBook(loan, book.Title, ""))
+	}
+	return c.JSON(fiber.Map{
+		"loans": response,
+	})
+}
+
+// GetBorrowedBooks lists the books other users have lent to the authenticated user, once accepted, and not yet got back
+func GetBorrowedBooks(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Borrowed Books request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var loans []models.Loan
+	if err := database.DB.Preload("Book").Preload("User").
+		Where("borrower_user_id = ? AND accepted_at IS NOT NULL AND returned_at IS NULL", uiidStr).
+		Where(activeBookIDs("book_id")).
+		Order("due_at NULLS LAST, lent_at").
+		Find(&loans).Error; err != nil {
+		sugar.Errorw("Failed to get borrowed books", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get borrowed books",
+		})
+	}
+
+	response := make([]loanWithBook, 0, len(loans))
+	for _, loan := range loans {
+		response = append(response, newLoanWithBook(loan, loan.Book.Title, loan.User.Name))
+	}
+	return c.JSON(fiber.Map{
+		"loans": response,
+	})
+}
+
+// GetLoanRequests lists the loans other users offered to the authenticated user, waiting for an answer
+func GetLoanRequests(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Loan Requests request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var loans []models.Loan
+	if err := database.DB.Preload("Book").Preload("User").
+		Where("borrower_user_id = ? AND accepted_at IS NULL AND returned_at IS NULL", uiidStr).
+		Where(activeBookIDs("book_id")).
+		Order("lent_at").
+		Find(&loans).Error; err != nil {
+		sugar.Errorw("Failed to get loan requests", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get loan requests",
+		})
+	}
+
+	response := make([]loanWithBook, 0, len(loans))
+	for _, loan := range loans {
+		response = append(response, newLoanWithBook(loan, loan.Book.Title, loan.User.Name))
+	}
+	return c.JSON(fiber.Map{
+		"loans": response,
+	})
+}
+
+// AcceptLoan links a loan offered to the authenticated user to their borrowed books
+func AcceptLoan(c *fiber.Ctx) error {
+	sugar.Info("Received an Accept Loan request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	loan, err := findLoanRequest(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Loan not found",
+		})
+	}
+
+	now := time.Now()
+	loan.AcceptedAt = &now
+	if err := database.DB.Model(&loan).Update("accepted_at", loan.AcceptedAt).Error; err != nil {
+		sugar.Errorw("Failed to accept loan", "loanID", loan.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to accept loan",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Loan accepted successfully",
+		"loan":    newLoanWithBook(loan, loan.Book.Title, loan.User.Name),
+	})
+}
+
+// DeclineLoan detaches a loan offered to the authenticated user from their account, the lender keeps it
+func DeclineLoan(c *fiber.Ctx) error {
+	sugar.Info("Received a Decline Loan request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	loan, err := findLoanRequest(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Loan not found",
+		})
+	}
+
+	if err := database.DB.Model(&loan).Update("borrower_user_id", nil).Error; err != nil {
+		sugar.Errorw("Failed to decline loan", "loanID", loan.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to decline loan",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Loan declined successfully",
+	})
+}
+
+// findLoanRequest returns a loan offered to the user that is neither accepted nor returned
+func findLoanRequest(userID string, loanID string) (models.Loan, error) {
+	var loan models.Loan
+	if _, err := uuid.Parse(loanID); err != nil {
+		return loan, err
+	}
+	err := database.DB.Preload("Book").Preload("User").
+		Where("id = ? AND borrower_user_id = ? AND accepted_at IS NULL AND returned_at IS NULL", loanID, userID).
+		First(&loan).Error
+	return loan, err
+}
+
+func newLoanWithBook(loan models.Loan, bookTitle string, lenderName string) loanWithBook {
+	return loanWithBook{
+		Loan:       loan,
+		BookTitle:  bookTitle,
+		LenderName: lenderName,
+		Overdue:    loan.IsOverdue(time.Now()),
+	}
+}
+
+// SendLoanReminders emails the borrowers of overdue loans that asked for reminders,
+// at most once every intervalDays per loan and maxLoanRemindersPerLender per lender, and returns the number of emails sent
+func SendLoanReminders(intervalDays int) (int, error) {
+	now := time.Now()
+	var loans []models.Loan
+	if err := database.DB.Preload("Book").Preload("User").
+		Where("returned_at IS NULL AND send_reminders = ? AND borrower_email <> ''", true).
+		Where("due_at < ?", now.Format("2006-01-02")).
+		Where("(reminded_at IS NULL OR reminded_at < ?)", now.AddDate(0, 0, -intervalDays)).
+		Where(activeBookIDs("book_id")).
+		Order("reminded_at NULLS FIRST, due_at").
+		Find(&loans).Error; err != nil {
+		return 0, err
+	}
+
+	service := services.NewEmailService(database.DB)
+	sent := 0
+	perLender := make(map[string]int)
+	for _, loan := range loans {
+		// Les rappels les plus anciens passent en premier, le reste attend la prochaine recherche
+		if perLender[loan.UserID] >= maxLoanRemindersPerLender {
+			continue
+		}
+		perLender[loan.UserID]++
+		if err := service.SendLoanReminderEmail(loan.BorrowerEmail, loan.BorrowerName, loan.User.Name, loan.Book.Title, *loan.DueAt); err != nil {
+			sugar.Errorw("Failed to send loan reminder", "loanID", loan.ID, "error", err)
+			continue
+		}
+		if err := database.DB.Model(&loan).Update("reminded_at", now).Error; err != nil {
+			sugar.Errorw("Failed to save loan reminder date", "loanID", loan.ID, "error", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartLoanReminders checks the overdue loans now and then once a day, unless the interval is 0
+func StartLoanReminders(intervalDays int) {
+	if intervalDays <= 0 {
+		sugar.Info("Loan reminder emails disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(loanReminderCheckInterval)
+		defer ticker.Stop()
+		for {
+			sent, err := SendLoanReminders(intervalDays)
+			if err != nil {
+				sugar.Errorw("Failed to send loan reminders", "error", err)
+			} else if sent > 0 {
+				sugar.Infow("Loan reminders sent", "emails", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
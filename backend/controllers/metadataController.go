@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/services"
+	"booksrendezvous-backend/utils"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchMetadata searches the metadata providers, falling back to Open Library when Google has no result
+func SearchMetadata(c *fiber.Ctx) error {
+	sugar.Info("Received a metadata search request")
+
+	if _, ok := CheckAuth(c); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Query parameter q is required",
+		})
+	}
+
+	service := services.NewMetadataService(database.DB)
+	results, err := service.Search(query, c.QueryInt("limit", 10))
+	if err != nil {
+		sugar.Errorw("Metadata search failed", "query", query, "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to fetch metadata",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"results": results,
+	})
+}
+
+// GetMetadataVolume returns the metadata of a Google Books volume completed by the other providers
+func GetMetadataVolume(c *fiber.Ctx) error {
+	sugar.Info("Received a metadata volume request")
+
+	if _, ok := CheckAuth(c); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	service := services.NewMetadataService(database.DB)
+	volume, err := service.Volume(c.Params("id"))
+	if errors.Is(err, services.ErrMetadataNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Volume not found",
+		})
+	}
+	if err != nil {
+		sugar.Errorw("Metadata volume lookup failed", "volumeID", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Failed to fetch metadata",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"volume": volume,
+	})
+}
+
+// enrichBookRequest fills the empty fields of a book request from the metadata providers
+func enrichBookRequest(book *Book) {
+	if book.GoogleBooksID == "" && book.ISBN == "" {
+		return
+	}
+
+	service := services.NewMetadataService(database.DB)
+
+	var metadata *services.BookMetadata
+	var err error
+	if book.GoogleBooksID != "" {
+		metadata, err = service.Volume(book.GoogleBooksID)
+	}
+	if metadata == nil && book.ISBN != "" {
+		metadata, err = service.LookupISBN(book.ISBN)
+	}
+	if metadata == nil {
+		// L'enrichissement est optionnel, l'ajout continue avec les données reçues
+		sugar.Warnw("Failed to enrich book from metadata providers",
+			"googleBooksId", book.GoogleBooksID,
+			"isbn", book.ISBN,
+			"error", err,
+		)
+		return
+	}
+
+	if book.GoogleBooksID == "" {
+		book.GoogleBooksID = metadata.GoogleBooksID
+	}
+	if book.ISBN == "" {
+		for _, isbn := range []string{metadata.ISBN13, metadata.ISBN10} {
+			if _, _, err := utils.NormalizeISBN(isbn); err == nil {
+				book.ISBN = isbn
+				break
+			}
+		}
+	}
+	if book.Title == "" {
+		book.Title = metadata.Title
+	}
+	if len(book.Authors) == 0 {
+		book.Authors = metadata.Authors
+	}
+	if book.Description == "" {
+		book.Description = metadata.Description
+	}
+	if book.Publisher == "" {
+		book.Publisher = metadata.Publisher
+	}
+	if book.Language == "" {
+		book.Language = metadata.Language
+	}
+	if book.ImageURL == "" {
+		book.ImageURL = metadata.ImageURL
+	}
+	if book.PageCount == 0 {
+		book.PageCount = metadata.PageCount
+	}
+	if len(book.Genres) == 0 {
+		book.Genres = metadata.Genres
+	}
+	if book.PublishedDate == "" {
+		book.PublishedDate = metadata.PublishedDate
+	}
+	if book.SeriesID == "" && book.SeriesName == "" {
+		book.SeriesName = metadata.SeriesName
+	}
+	if book.VolumeNumber == 0 && (book.SeriesID != "" || book.SeriesName != "") {
+		book.VolumeNumber = metadata.VolumeNumber
+	}
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Taille maximale du corps d'une note
+const maxNoteLength = 20000
+
+var errNoteNotFound = errors.New("Note not found")
+
+type NoteRequest struct {
+	Type     string `json:"type"`
+	Body     string `json:"body"`
+	Page     int    `json:"page"`
+	Location string `json:"location"`
+	IsPublic bool   `json:"isPublic"`
+}
+
+// NoteQuery holds the filters accepted by GET /api/notes
+type NoteQuery struct {
+	Search string `query:"q"`      // Recherche dans le texte des notes
+	Type   string `query:"type"`   // quote, highlight ou note
+	BookID string `query:"bookId"` // Notes d'un seul livre
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+}
+
+// noteWithBook is a note returned by the search, with the title of its book
+type noteWithBook struct {
+	models.BookNote `gorm:"embedded"`
+	BookTitle       string `json:"bookTitle"`
+}
+
+// GetBookNotes returns the notes of a book ordered by page
+func GetBookNotes(c *fiber.Ctx) error {
+	sugar.Info("Received a book notes request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	var notes []models.BookNote
+	if err := database.DB.Where("book_id = ?", book.ID).Order("page, created_at").Find(&notes).Error; err != nil {
+		sugar.Errorw("Failed to get notes", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get notes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"notes": notes,
+	})
+}
+
+// AddNote adds a quote, highlight or note to a book
+func AddNote(c *fiber.Ctx) error {
+	sugar.Info("Received an add note request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request NoteRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	note := models.BookNote{
+		UserID: uiidStr,
+		BookID: book.ID,
+	}
+	if err := applyNoteRequest(&note, request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Create(&note).Error; err != nil {
+		sugar.Errorw("Failed to create note", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create note",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Note added successfully",
+		"note":    note,
+	})
+}
+
+// UpdateNote replaces the content of a note
+func UpdateNote(c *fiber.Ctx) error {
+	sugar.Info("Received an update note request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request NoteRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	note, err := findUserNote(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := applyNoteRequest(&note, request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Save(&note).Error; err != nil {
+		sugar.Errorw("Failed to update note", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update note",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Note updated successfully",
+		"note":    note,
+	})
+}
+
+// DeleteNote removes a note
+func DeleteNote(c *fiber.Ctx) error {
+	sugar.Info("Received a delete note request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	note, err := findUserNote(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Delete(&note).Error; err != nil {
+		sugar.Errorw("Failed to delete note", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete note",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Note deleted successfully",
+	})
+}
+
+// SearchNotes searches across all the notes of the user
+func SearchNotes(c *fiber.Ctx) error {
+	sugar.Info("Received a notes search request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var params NoteQuery
+	if err := c.QueryParser(&params); err != nil {
+		sugar.Errorw("Failed to parse query parameters", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse query parameters",
+		})
+	}
+	if params.Limit < 0 || params.Offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit and offset cannot be negative",
+		})
+	}
+	if params.Limit == 0 || params.Limit > maxBooksPageSize {
+		params.Limit = maxBooksPageSize
+	}
+
+	query := database.DB.Table("book_notes").
+		Joins("JOIN books ON books.id = book_notes.book_id").
+		Where("book_notes.user_id = ? AND books.deleted_at IS NULL", uiidStr)
+
+	if params.Type != "" {
+		if !isValidNoteType(params.Type) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid note type filter",
+			})
+		}
+		query = query.Where("book_notes.type = ?", params.Type)
+	}
+	if params.BookID != "" {
+		query = query.Where("book_notes.book_id = ?", params.BookID)
+	}
+	if params.Search != "" {
+		pattern := "%" + escapeLike(params.Search) + "%"
+		query = query.Where("(book_notes.body ILIKE ? OR book_notes.location ILIKE ? OR books.title ILIKE ?)", pattern, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		sugar.Errorw("Failed to count notes", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search notes",
+		})
+	}
+
+	notes := []noteWithBook{}
+	if err := query.Session(&gorm.Session{}).Select("book_notes.*, books.title AS book_title").
+		Order("book_notes.created_at DESC, book_notes.id").
+		Limit(params.Limit).Offset(params.Offset).
+		Scan(&notes).Error; err != nil {
+		sugar.Errorw("Failed to search notes", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search notes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"notes":  notes,
+		"total":  total,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+}
+
+func findUserNote(userID string, noteID string) (models.BookNote, error) {
+	var note models.BookNote
+	if err := database.DB.First(&note, "id = ? AND user_id = ?", noteID, userID).Error; err != nil {
+		sugar.Errorw("Note not found", "noteID", noteID, "error", err)
+		return note, errNoteNotFound
+	}
+	return note, nil
+}
+
+// applyNoteRequest validates the request and copies it onto the note
+func applyNoteRequest(note *models.BookNote, request NoteRequest) error {
+	if request.Type == "" {
+		request.Type = models.NoteNote
+	}
+	if !isValidNoteType(request.Type) {
+		return errors.New("Note type must be quote, highlight or note")
+	}
+	body := strings.TrimSpace(request.Body)
+	if body == "" {
+		return errors.New("Note body is required")
+	}
+	if len(body) > maxNoteLength {
+		return errors.New("Note body is too long")
+	}
+	if request.Page < 0 {
+		return errors.New("Page cannot be negative")
+	}
+	if len(request.Location) > 64 {
+		return errors.New("Location is too long")
+	}
+
+	note.Type = request.Type
+	note.Body = body
+	note.Page = request.Page
+	note.Location = strings.TrimSpace(request.Location)
+	note.IsPublic = request.IsPublic
+	return nil
+}
+
+func isValidNoteType(noteType string) bool {
+	switch noteType {
+	case models.NoteQuote, models.NoteHighlight, models.NoteNote:
+		return true
+	default:
+		return false
+	}
+}
@@ -44,8 +44,25 @@ func GetPublicUser(c *fiber.Ctx) error {
 	var books []models.Book
 	database.DB.Where("user_id = ?", publicusers.UserID).Find(&books)
 
+	// only the shelves marked public are shared with the profile
+	var shelves []models.Shelf
+	database.DB.Where("user_id = ? AND is_public = ?", publicusers.UserID, true).Order("name").Find(&shelves)
+	publicShelves, err := buildShelfResponses(shelves)
+	if err != nil {
+		sugar.Errorw("Failed to get public shelves", "error", err)
+		publicShelves = []shelfResponse{}
+	}
+
+	// public notes only, private ones stay hidden even on a public profile
+	var notes []models.BookNote
+	database.DB.Where("user_id = ? AND is_public = ?", publicusers.UserID, true).
+		Where(activeBookIDs("book_id")).
+		Order("created_at DESC").Find(&notes)
+
 	return c.JSON(fiber.Map{
-		"books": books,
+		"books":   books,
+		"shelves": publicShelves,
+		"notes":   notes,
 	})
 }
 
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errSeriesNotFound = errors.New("Series not found")
+
+type SeriesRequest struct {
+	Name         string `json:"name"`
+	TotalVolumes int    `json:"totalVolumes"`
+}
+
+// seriesSummary is the completion state of a series, e.g. 3 of 7 volumes read
+type seriesSummary struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	TotalVolumes int           `json:"totalVolumes"` // Nombre de tomes connu, sinon estimé d'après les livres possédés
+	KnownTotal   bool          `json:"knownTotal"`
+	OwnedVolumes int           `json:"ownedVolumes"`
+	ReadVolumes  int           `json:"readVolumes"` // Numéros de tome distincts lus, hors tomes sans numéro
+	Completed    bool          `json:"completed"`
+	NextVolume   *seriesVolume `json:"nextVolume"`
+	Books        []models.Book `json:"books,omitempty"`
+}
+
+// seriesVolume is the next volume to read, Book is nil when the volume is not in the library
+type seriesVolume struct {
+	Number int          `json:"number"`
+	Book   *models.Book `json:"book"`
+}
+
+// GetSeries lists the user's series with their completion state
+func GetSeries(c *fiber.Ctx) error {
+	sugar.Info("Received a series request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	summaries, err := loadSeriesSummaries(database.DB, uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to get series", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get series",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"series": summaries,
+	})
+}
+
+// GetSeriesDetail returns a series with its books ordered by volume number
+func GetSeriesDetail(c *fiber.Ctx) error {
+	sugar.Info("Received a series detail request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	series, err := findUserSeries(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var books []models.Book
+	if err := database.DB.Where("user_id = ? AND series_id = ?", uiidStr, series.ID).
+		Order("volume_number = 0, volume_number, title").
+		Find(&books).Error; err != nil {
+		sugar.Errorw("Failed to get series books", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get series books",
+		})
+	}
+
+	summary := summarizeSeries(series, books)
+	summary.Books = books
+
+	return c.JSON(fiber.Map{
+		"series": summary,
+	})
+}
+
+// CreateSeries creates an empty series
+func CreateSeries(c *fiber.Ctx) error {
+	sugar.Info("Received a create series request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request SeriesRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" || request.TotalVolumes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Series name is required and total volumes cannot be negative",
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.Series{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", uiidStr, name).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A series with this name already exists",
+		})
+	}
+
+	series := models.Series{
+		UserID:       uiidStr,
+		Name:         name,
+		TotalVolumes: request.TotalVolumes,
+	}
+	if err := database.DB.Create(&series).Error; err != nil {
+		sugar.Errorw("Failed to create series", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create series",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Series created successfully",
+		"series":  series,
+	})
+}
+
+// UpdateSeries renames a series or changes its number of volumes
+func UpdateSeries(c *fiber.Ctx) error {
+	sugar.Info("Received an update series request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request SeriesRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" || request.TotalVolumes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Series name is required and total volumes cannot be negative",
+		})
+	}
+
+	series, err := findUserSeries(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var count int64
+	database.DB.Model(&models.Series{}).Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", uiidStr, name, series.ID).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A series with this name already exists",
+		})
+	}
+
+	series.Name = name
+	series.TotalVolumes = request.TotalVolumes
+	// Le nombre de tomes change l'état de complétion de la série
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&series).Error; err != nil {
+			return err
+		}
+		return refreshSeriesStats(tx, userID)
+	}); err != nil {
+		sugar.Errorw("Failed to update series", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update series",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Series updated successfully",
+		"series":  series,
+	})
+}
+
+// DeleteSeries removes a series, its books are kept without series
+func DeleteSeries(c *fiber.Ctx) error {
+	sugar.Info("Received a delete series request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	series, err := findUserSeries(uiidStr, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var books []models.Book
+		if err := tx.Where("series_id = ?", series.ID).Find(&books).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Book{}).Where("series_id = ?", series.ID).
+			Updates(map[string]interface{}{"series_id": nil, "volume_number": 0}).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			updated := book
+			updated.SeriesID, updated.VolumeNumber = nil, 0
+			if err := recordBookHistory(tx, models.HistoryUpdate, &book, updated); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&series).Error; err != nil {
+			return err
+		}
+		return refreshSeriesStats(tx, userID)
+	}); err != nil {
+		sugar.Errorw("Failed to delete series", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete series",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Series deleted successfully",
+	})
+}
+
+func findUserSeries(userID string, seriesID string) (models.Series, error) {
+	var series models.Series
+	if err := database.DB.First(&series, "id = ? AND user_id = ?", seriesID, userID).Error; err != nil {
+		sugar.Errorw("Series not found", "seriesID", seriesID, "error", err)
+		return series, errSeriesNotFound
+	}
+	return series, nil
+}
+
+// findOrCreateSeries returns the user's series with this name, creating it when needed
+func findOrCreateSeries(userID string, name string) (models.Series, error) {
+	var series models.Series
+	name = strings.TrimSpace(name)
+	err := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).First(&series).Error
+	if err == nil {
+		return series, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return series, err
+	}
+
+	series = models.Series{UserID: userID, Name: name}
+	return series, database.DB.Create(&series).Error
+}
+
+// applySeries links the book to the series given by ID or by name
+func applySeries(userID string, book *models.Book, request Book) error {
+	if request.VolumeNumber < 0 {
+		return errors.New("Volume number cannot be negative")
+	}
+
+	switch {
+	case request.SeriesID != "":
+		series, err := findUserSeries(userID, request.SeriesID)
+		if err != nil {
+			return err
+		}
+		book.SeriesID = &series.ID
+	case strings.TrimSpace(request.SeriesName) != "":
+		series, err := findOrCreateSeries(userID, request.SeriesName)
+		if err != nil {
+			sugar.Errorw("Failed to create series", "name", request.SeriesName, "error", err)
+			return errors.New("Failed to create series")
+		}
+		book.SeriesID = &series.ID
+	default:
+		book.SeriesID = nil
+	}
+
+	book.VolumeNumber = request.VolumeNumber
+	if book.SeriesID == nil {
+		book.VolumeNumber = 0
+	}
+	return nil
+}
+
+// loadSeriesSummaries computes the completion state of every series of the user
+func loadSeriesSummaries(db *gorm.DB, userID string) ([]seriesSummary, error) {
+	var series []models.Series
+	if err := db.Where("user_id = ?", userID).Order("name").Find(&series).Error; err != nil {
+		return nil, err
+	}
+
+	var books []models.Book
+	if err := db.Where("user_id = ? AND series_id IS NOT NULL", userID).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	booksBySeries := make(map[string][]models.Book, len(series))
+	for _, book := range books {
+		booksBySeries[*book.SeriesID] = append(booksBySeries[*book.SeriesID], book)
+	}
+
+	summaries := make([]seriesSummary, 0, len(series))
+	for _, s := range series {
+		summaries = append(summaries, summarizeSeries(s, booksBySeries[s.ID]))
+	}
+	return summaries, nil
+}
+
+// summarizeSeries counts owned and read volumes and finds the next volume to read
+func summarizeSeries(series models.Series, books []models.Book) seriesSummary {
+	summary := seriesSummary{
+		ID:           series.ID,
+		Name:         series.Name,
+		TotalVolumes: series.TotalVolumes,
+		KnownTotal:   series.TotalVolumes > 0,
+		OwnedVolumes: len(books),
+	}
+
+	read := make(map[int]bool)
+	owned := make(map[int]*models.Book)
+	var firstUnnumbered *models.Book
+	for i := range books {
+		book := &books[i]
+		finished := book.Status == models.StatusFinished
+		if book.VolumeNumber == 0 {
+			// Tome sans numéro: ignoré pour l'avancement, proposé en dernier recours
+			if !finished && firstUnnumbered == nil {
+				firstUnnumbered = book
+			}
+			continue
+		}
+		if finished && !read[book.VolumeNumber] {
+			read[book.VolumeNumber] = true
+			summary.ReadVolumes++
+		}
+		if current, ok := owned[book.VolumeNumber]; !ok || current.Status == models.StatusFinished {
+			owned[book.VolumeNumber] = book
+		}
+		if !summary.KnownTotal && book.VolumeNumber > summary.TotalVolumes {
+			summary.TotalVolumes = book.VolumeNumber
+		}
+	}
+	if !summary.KnownTotal && summary.OwnedVolumes > summary.TotalVolumes {
+		summary.TotalVolumes = summary.OwnedVolumes
+	}
+
+	// Premier tome non lu dans l'ordre de la série, possédé ou non
+	allRead := true
+	for number := 1; number <= summary.TotalVolumes; number++ {
+		if !read[number] {
+			allRead = false
+			summary.NextVolume = &seriesVolume{Number: number, Book: owned[number]}
+			break
+		}
+	}
+	if summary.NextVolume == nil && firstUnnumbered != nil {
+		summary.NextVolume = &seriesVolume{Book: firstUnnumbered}
+	}
+
+	// Une série n'est terminée que si son nombre de tomes est connu et que chacun de ses numéros est lu
+	summary.Completed = summary.KnownTotal && allRead
+	return summary
+}
+
+// countCompletedSeries returns the number of series of the user whose volumes are all read
+func countCompletedSeries(db *gorm.DB, userID uuid.UUID) int {
+	summaries, err := loadSeriesSummaries(db, userID.String())
+	if err != nil {
+		sugar.Errorw("Failed to compute completed series", "userID", userID, "error", err)
+		return 0
+	}
+
+	completed := 0
+	for _, summary := range summaries {
+		if summary.Completed {
+			completed++
+		}
+	}
+	return completed
+}
+
+// refreshSeriesStats recounts the completed series of the user inside the transaction that changed a series
+func refreshSeriesStats(tx *gorm.DB, userID uuid.UUID) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
+	return tx.Model(&userstats).Update("completed_series", countCompletedSeries(tx, userID)).Error
+}
@@ -0,0 +1,486 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRequest struct {
+	Pages   int    `json:"pages"`
+	Minutes int    `json:"minutes"`
+	Date    string `json:"date"`
+	Note    string `json:"note"`
+}
+
+type dailyReading struct {
+	Date     time.Time `json:"date"`
+	Pages    int       `json:"pages"`
+	Minutes  int       `json:"minutes"`
+	Sessions int       `json:"sessions"`
+}
+
+// GetBookSessions returns the reading sessions of a book
+func GetBookSessions(c *fiber.Ctx) error {
+	sugar.Info("Received a book sessions request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	var sessions []models.ReadingSession
+	if err := database.DB.Where("book_id = ?", book.ID).Order("date DESC, created_at DESC").Find(&sessions).Error; err != nil {
+		sugar.Errorw("Failed to get sessions", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+	})
+}
+
+// LogSession records a finished reading session and advances the book progress
+func LogSession(c *fiber.Ctx) error {
+	sugar.Info("Received a log session request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request SessionRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	if request.Pages < 0 || request.Minutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Pages and minutes cannot be negative",
+		})
+	}
+	if request.Pages == 0 && request.Minutes == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A session needs pages or minutes",
+		})
+	}
+
+	date, err := parseBookDate(request.Date)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid session date",
+		})
+	}
+	if date == nil {
+		now := time.Now()
+		date = &now
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	session := models.ReadingSession{
+		UserID:  uiidStr,
+		BookID:  book.ID,
+		Date:    *date,
+		Pages:   request.Pages,
+		Minutes: request.Minutes,
+		Note:    request.Note,
+	}
+
+	// La session et l'avancement du livre sont enregistrés ensemble
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updated, err := advanceBookProgress(tx, userID, book, sessionProgress(book, session))
+		if err != nil {
+			return err
+		}
+		applied := updated.Progress - book.Progress
+		session.AppliedProgress = &applied
+		book = updated
+
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return OnReadingDayUpdateStats(tx, userID, session.Date)
+	}); err != nil {
+		sugar.Errorw("Failed to save session", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save session",
+		})
+	}
+	checkSessionAchievements(userID)
+
+	return c.JSON(fiber.Map{
+		"message": "Session logged successfully",
+		"session": session,
+		"book":    book,
+	})
+}
+
+// StartSession opens a timed reading session on a book
+func StartSession(c *fiber.Ctx) error {
+	sugar.Info("Received a start session request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findUserBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Une seule session en cours par livre
+	var running int64
+	database.DB.Model(&models.ReadingSession{}).
+		Where("book_id = ? AND started_at IS NOT NULL AND ended_at IS NULL", book.ID).
+		Count(&running)
+	if running > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A session is already running for this book",
+		})
+	}
+
+	now := time.Now()
+	session := models.ReadingSession{
+		UserID:    uiidStr,
+		BookID:    book.ID,
+		Date:      now,
+		StartedAt: &now,
+	}
+
+	// L'index unique départage deux démarrages simultanés, voir database.ConnectDB
+	err = database.DB.Create(&session).Error
+	if isUniqueViolation(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A session is already running for this book",
+		})
+	}
+	if err != nil {
+		sugar.Errorw("Failed to start session", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start session",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session started successfully",
+		"session": session,
+	})
+}
+
+// StopSession closes a running session, computing its duration unless provided
+func StopSession(c *fiber.Ctx) error {
+	sugar.Info("Received a stop session request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request SessionRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	if request.Pages < 0 || request.Minutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Pages and minutes cannot be negative",
+		})
+	}
+
+	var session models.ReadingSession
+	if err := database.DB.First(&session, "id = ? AND user_id = ?", c.Params("id"), userID).Error; err != nil {
+		sugar.Errorw("Session not found", "sessionID", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+
+	if !session.IsRunning() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Session is not running",
+		})
+	}
+
+	book, err := findUserBook(userID, session.BookID)
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	now := time.Now()
+	session.EndedAt = &now
+	session.Pages = request.Pages
+	session.Minutes = request.Minutes
+	if session.Minutes == 0 {
+		session.Minutes = int(now.Sub(*session.StartedAt).Minutes())
+	}
+	if request.Note != "" {
+		session.Note = request.Note
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updated, err := advanceBookProgress(tx, userID, book, sessionProgress(book, session))
+		if err != nil {
+			return err
+		}
+		applied := updated.Progress - book.Progress
+		session.AppliedProgress = &applied
+		book = updated
+
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+		return OnReadingDayUpdateStats(tx, userID, session.Date)
+	}); err != nil {
+		sugar.Errorw("Failed to stop session", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to stop session",
+		})
+	}
+	checkSessionAchievements(userID)
+
+	return c.JSON(fiber.Map{
+		"message": "Session stopped successfully",
+		"session": session,
+		"book":    book,
+	})
+}
+
+// DeleteSession removes a session and rolls back the progress it contributed
+func DeleteSession(c *fiber.Ctx) error {
+	sugar.Info("Received a delete session request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var session models.ReadingSession
+	if err := database.DB.First(&session, "id = ? AND user_id = ?", c.Params("id"), userID).Error; err != nil {
+		sugar.Errorw("Session not found", "sessionID", c.Params("id"), "error", err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+
+	book, err := findUserBook(userID, session.BookID)
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Seul l'avancement réellement appliqué par la session est retiré
+	amount := sessionProgress(book, session)
+	if session.AppliedProgress != nil {
+		amount = *session.AppliedProgress
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&session).Error; err != nil {
+			return err
+		}
+		if _, err := advanceBookProgress(tx, userID, book, -amount); err != nil {
+			return err
+		}
+		return refreshStreaks(tx, userID)
+	}); err != nil {
+		sugar.Errorw("Failed to delete session", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete session",
+		})
+	}
+	checkSessionAchievements(userID)
+
+	return c.JSON(fiber.Map{
+		"message": "Session deleted successfully",
+	})
+}
+
+// GetDailyReading returns pages and minutes read per day over a date range
+func GetDailyReading(c *fiber.Ctx) error {
+	sugar.Info("Received a daily reading request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	// Par défaut les 30 derniers jours
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if value, err := parseBookDate(c.Query("from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid from date",
+		})
+	} else if value != nil {
+		from = *value
+	}
+	if value, err := parseBookDate(c.Query("to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid to date",
+		})
+	} else if value != nil {
+		to = *value
+	}
+
+	var days []dailyReading
+	if err := database.DB.Model(&models.ReadingSession{}).
+		Select("date, SUM(pages) AS pages, SUM(minutes) AS minutes, COUNT(*) AS sessions").
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Where("NOT (started_at IS NOT NULL AND ended_at IS NULL)").
+		Where(activeBookIDs("book_id")).
+		Group("date").
+		Order("date").
+		Scan(&days).Error; err != nil {
+		sugar.Errorw("Failed to get daily reading", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get daily reading",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"days": days,
+	})
+}
+
+// sessionProgress converts a session into the progress unit of the book:
+// minutes for audiobooks, pages converted to percent when the page count is known
+func sessionProgress(book models.Book, session models.ReadingSession) int {
+	switch book.ProgressUnit {
+	case models.ProgressMinutes:
+		return session.Minutes
+	case models.ProgressPercent:
+		if book.PageCount > 0 {
+			return session.Pages * 100 / book.PageCount
+		}
+		return 0
+	default:
+		return session.Pages
+	}
+}
+
+// advanceBookProgress moves the book progress by the given amount, in the book progress unit,
+// and updates the history and stats inside the transaction of the session
+func advanceBookProgress(tx *gorm.DB, userID uuid.UUID, book models.Book, amount int) (models.Book, error) {
+	if amount == 0 {
+		return book, nil
+	}
+
+	updated := book
+	updated.Progress += amount
+	if updated.Progress < 0 {
+		updated.Progress = 0
+	}
+	if limit := progressLimit(updated); limit > 0 && updated.Progress > limit {
+		updated.Progress = limit
+	}
+
+	// Une session sur un livre à lire démarre la lecture
+	if amount > 0 && updated.Status == models.StatusToRead {
+		updated.Status = models.StatusReading
+	}
+	if err := normalizeReadingTracking(&updated, book.Status); err != nil {
+		return book, err
+	}
+
+	updated.Version++
+	if err := tx.Save(&updated).Error; err != nil {
+		return book, err
+	}
+	if err := recordBookHistory(tx, models.HistorySession, &book, updated); err != nil {
+		return book, err
+	}
+	if err := OnChangeUpdateStats(tx, userID, updated, book); err != nil {
+		return book, err
+	}
+	return updated, nil
+}
+
+// checkSessionAchievements checks the achievements once a session is saved, sessions count for the reading goals
+func checkSessionAchievements(userID uuid.UUID) {
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(userID.String()); err != nil {
+		sugar.Errorw("Failed to check achievements", "userID", userID, "error", err)
+	}
+}
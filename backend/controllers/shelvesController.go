@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxShelfNameLength = 100
+
+var (
+	errShelfNotFound = errors.New("Shelf not found")
+	errShelfExists   = errors.New("A shelf with this name already exists")
+)
+
+type ShelfRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"isPublic"`
+}
+
+type ShelfBookRequest struct {
+	BookID string `json:"bookId"`
+}
+
+type ShelfOrderRequest struct {
+	BookIDs []string `json:"bookIds"`
+}
+
+type shelfResponse struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	IsPublic    bool     `json:"isPublic"`
+	BookCount   int      `json:"bookCount"`
+	BookIDs     []string `json:"bookIds"`
+}
+
+// GetShelves lists the user's shelves with the ordered IDs of their books
+func GetShelves(c *fiber.Ctx) error {
+	sugar.Info("Received a shelves request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var shelves []models.Shelf
+	if err := database.DB.Where("user_id = ?", uiidStr).Order("name").Find(&shelves).Error; err != nil {
+		sugar.Errorw("Failed to get shelves", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get shelves",
+		})
+	}
+
+	response, err := buildShelfResponses(shelves)
+	if err != nil {
+		sugar.Errorw("Failed to get shelf books", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get shelves",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"shelves": response,
+	})
+}
+
+// GetShelf returns a shelf and its books in shelf order
+func GetShelf(c *fiber.Ctx) error {
+	sugar.Info("Received a shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	var books []models.Book
+	if err := database.DB.
+		Joins("JOIN shelf_books ON shelf_books.book_id = books.id").
+		Where("shelf_books.shelf_id = ?", shelf.ID).
+		Order("shelf_books.position, shelf_books.added_at").
+		Find(&books).Error; err != nil {
+		sugar.Errorw("Failed to get shelf books", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get shelf books",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"shelf": shelf,
+		"books": books,
+	})
+}
+
+// CreateShelf creates a new shelf for the user
+func CreateShelf(c *fiber.Ctx) error {
+	sugar.Info("Received a create shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request ShelfRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	name, err := validateShelfName(request.Name)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if shelfNameTaken(uiidStr, name, "") {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": errShelfExists.Error(),
+		})
+	}
+
+	shelf := models.Shelf{
+		UserID:      uiidStr,
+		Name:        name,
+		Description: request.Description,
+		IsPublic:    request.IsPublic,
+	}
+	if err := database.DB.Create(&shelf).Error; err != nil {
+		sugar.Errorw("Failed to create shelf", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shelf created successfully",
+		"shelf":   shelf,
+	})
+}
+
+// UpdateShelf renames a shelf or changes its description and visibility
+func UpdateShelf(c *fiber.Ctx) error {
+	sugar.Info("Received an update shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request ShelfRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	name, err := validateShelfName(request.Name)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if shelfNameTaken(uiidStr, name, shelf.ID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": errShelfExists.Error(),
+		})
+	}
+
+	shelf.Name = name
+	shelf.Description = request.Description
+	shelf.IsPublic = request.IsPublic
+
+	if err := database.DB.Save(&shelf).Error; err != nil {
+		sugar.Errorw("Failed to update shelf", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shelf updated successfully",
+		"shelf":   shelf,
+	})
+}
+
+// DeleteShelf removes a shelf, the books themselves are kept
+func DeleteShelf(c *fiber.Ctx) error {
+	sugar.Info("Received a delete shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shelf_id = ?", shelf.ID).Delete(&models.ShelfBook{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&shelf).Error
+	}); err != nil {
+		sugar.Errorw("Failed to delete shelf", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shelf deleted successfully",
+	})
+}
+
+// AddBookToShelf appends one of the user's books at the end of a shelf
+func AddBookToShelf(c *fiber.Ctx) error {
+	sugar.Info("Received an add book to shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	var request ShelfBookRequest
+	if err := c.BodyParser(&request); err != nil || request.BookID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Book ID is required",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	book, err := findUserBook(userID, request.BookID)
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	var existing int64
+	database.DB.Model(&models.ShelfBook{}).Where("shelf_id = ? AND book_id = ?", shelf.ID, book.ID).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Book already on this shelf",
+		})
+	}
+
+	if err := addBooksToShelf(database.DB, shelf.ID, []string{book.ID}); err != nil {
+		sugar.Errorw("Failed to add book to shelf", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add book to shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Book added to shelf successfully",
+	})
+}
+
+// RemoveBookFromShelf takes a book off a shelf
+func RemoveBookFromShelf(c *fiber.Ctx) error {
+	sugar.Info("Received a remove book from shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	result := database.DB.Where("shelf_id = ? AND book_id = ?", shelf.ID, c.Params("bookId")).Delete(&models.ShelfBook{})
+	if result.Error != nil {
+		sugar.Errorw("Failed to remove book from shelf", "error", result.Error)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove book from shelf",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Book is not on this shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Book removed from shelf successfully",
+	})
+}
+
+// ReorderShelf sets the position of every book of a shelf from the given ordered list
+func ReorderShelf(c *fiber.Ctx) error {
+	sugar.Info("Received a reorder shelf request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var request ShelfOrderRequest
+	if err := c.BodyParser(&request); err != nil {
+		sugar.Errorw("Failed to parse request body", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse request body",
+		})
+	}
+
+	shelf, err := findUserShelf(uiidStr, c.Params("id"))
+	if err != nil {
+		return shelfLookupError(c, err)
+	}
+
+	// La liste doit contenir exactement les livres de l'étagère
+	var current []string
+	database.DB.Model(&models.ShelfBook{}).Where("shelf_id = ?", shelf.ID).Where(activeBookIDs("book_id")).Pluck("book_id", &current)
+	if !sameIDs(current, request.BookIDs) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Book IDs must list every book of the shelf exactly once",
+		})
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for position, bookID := range request.BookIDs {
+			if err := tx.Model(&models.ShelfBook{}).
+				Where("shelf_id = ? AND book_id = ?", shelf.ID, bookID).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		sugar.Errorw("Failed to reorder shelf", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reorder shelf",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Shelf reordered successfully",
+	})
+}
+
+// findUserShelf loads a shelf owned by the user
+func findUserShelf(userID string, shelfID string) (models.Shelf, error) {
+	var shelf models.Shelf
+	if err := database.DB.First(&shelf, "id = ? AND user_id = ?", shelfID, userID).Error; err != nil {
+		sugar.Errorw("Shelf not found", "shelfID", shelfID, "error", err)
+		return shelf, errShelfNotFound
+	}
+	return shelf, nil
+}
+
+func shelfLookupError(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+func validateShelfName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Shelf name is required")
+	}
+	if len([]rune(name)) > maxShelfNameLength {
+		return "", errors.New("Shelf name is too long")
+	}
+	return name, nil
+}
+
+// shelfNameTaken checks whether another shelf of the user already uses the name
+func shelfNameTaken(userID string, name string, exceptID string) bool {
+	query := database.DB.Model(&models.Shelf{}).Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// addBooksToShelf appends books at the end of a shelf, ignoring the ones already on it
+func addBooksToShelf(tx *gorm.DB, shelfID string, bookIDs []string) error {
+	var maxPosition int
+	if err := tx.Model(&models.ShelfBook{}).
+		Where("shelf_id = ?", shelfID).
+		Select("COALESCE(MAX(position), -1)").
+		Scan(&maxPosition).Error; err != nil {
+		return err
+	}
+
+	var existing []string
+	if err := tx.Model(&models.ShelfBook{}).Where("shelf_id = ? AND book_id IN ?", shelfID, bookIDs).Pluck("book_id", &existing).Error; err != nil {
+		return err
+	}
+	onShelf := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		onShelf[id] = true
+	}
+
+	for _, bookID := range bookIDs {
+		if onShelf[bookID] {
+			continue
+		}
+		maxPosition++
+		onShelf[bookID] = true
+		if err := tx.Create(&models.ShelfBook{ShelfID: shelfID, BookID: bookID, Position: maxPosition}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildShelfResponses attaches the ordered book IDs to each shelf
+func buildShelfResponses(shelves []models.Shelf) ([]shelfResponse, error) {
+	response := make([]shelfResponse, 0, len(shelves))
+	if len(shelves) == 0 {
+		return response, nil
+	}
+
+	shelfIDs := make([]string, 0, len(shelves))
+	for _, shelf := range shelves {
+		shelfIDs = append(shelfIDs, shelf.ID)
+	}
+
+	var links []models.ShelfBook
+	if err := database.DB.Where("shelf_id IN ?", shelfIDs).Where(activeBookIDs("book_id")).
+		Order("position, added_at").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	booksByShelf := make(map[string][]string, len(shelves))
+	for _, link := range links {
+		booksByShelf[link.ShelfID] = append(booksByShelf[link.ShelfID], link.BookID)
+	}
+
+	for _, shelf := range shelves {
+		bookIDs := booksByShelf[shelf.ID]
+		if bookIDs == nil {
+			bookIDs = []string{}
+		}
+		response = append(response, shelfResponse{
+			ID:          shelf.ID,
+			Name:        shelf.Name,
+			Description: shelf.Description,
+			IsPublic:    shelf.IsPublic,
+			BookCount:   len(bookIDs),
+			BookIDs:     bookIDs,
+		})
+	}
+	return response, nil
+}
+
+// sameIDs reports whether both lists contain the same IDs, each exactly once
+func sameIDs(expected []string, given []string) bool {
+	if len(expected) != len(given) {
+		return false
+	}
+	seen := make(map[string]bool, len(expected))
+	for _, id := range expected {
+		seen[id] = true
+	}
+	for _, id := range given {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/utils"
+	"math"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type breakdownEntry struct {
+	Name             string   `json:"name"` // Orthographe de la première occurrence
+	Books            int      `json:"books"`
+	FinishedBooks    int      `json:"finishedBooks"`
+	Pages            int      `json:"pages"` // Hors livres audio, comme TotalPages
+	ListeningMinutes int      `json:"listeningMinutes"`
+	RatedBooks       int      `json:"ratedBooks"`
+	AverageRating    *float64 `json:"averageRating"` // Nil si aucun livre n'est noté
+	FavoriteBooks    int      `json:"favoriteBooks"`
+
+	totalRating float64
+}
+
+// GetStatsBreakdown returns the count, pages, average rating and favorites of the books per genre
+// and per author, genres being normalized from the Google Books categories
+func GetStatsBreakdown(c *fiber.Ctx) error {
+	sugar.Info("Received a stats breakdown request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit cannot be negative",
+		})
+	}
+
+	var books []models.Book
+	if err := database.DB.Where("user_id = ?", userID).Find(&books).Error; err != nil {
+		sugar.Errorw("Failed to get books for stats breakdown", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get stats breakdown",
+		})
+	}
+
+	genres, authors := breakdownBooks(books)
+	return c.JSON(fiber.Map{
+		"genres":  limitBreakdown(genres, limit),
+		"authors": limitBreakdown(authors, limit),
+	})
+}
+
+// breakdownBooks aggregates the books per normalized genre and per author, largest groups first
+func breakdownBooks(books []models.Book) ([]breakdownEntry, []breakdownEntry) {
+	genres := make(map[string]*breakdownEntry)
+	authors := make(map[string]*breakdownEntry)
+	for _, book := range books {
+		for _, genre := range utils.NormalizeGenres(book.Genres) {
+			addToBreakdown(genres, utils.NormalizeTitle(genre), genre, book)
+		}
+		for _, author := range bookAuthors(book) {
+			addToBreakdown(authors, utils.NormalizeAuthorName(author), author, book)
+		}
+	}
+	return sortedBreakdown(genres), sortedBreakdown(authors)
+}
+
+// bookAuthors returns the authors of a book, each normalized name once
+func bookAuthors(book models.Book) []string {
+	authors := make([]string, 0, len(book.Authors))
+	seen := make(map[string]bool, len(book.Authors))
+	for _, author := range book.Authors {
+		key := utils.NormalizeAuthorName(author)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// addToBreakdown adds a book to the entry of the key, created with the given name on first use
+func addToBreakdown(entries map[string]*breakdownEntry, key string, name string, book models.Book) {
+	entry, found := entries[key]
+	if !found {
+		entry = &breakdownEntry{Name: name}
+		entries[key] = entry
+	}
+
+	entry.Books++
+	entry.Pages += printedPages(book)
+	entry.ListeningMinutes += bookListeningMinutes(book)
+	if book.Status == models.StatusFinished {
+		entry.FinishedBooks++
+	}
+	if book.Favorite {
+		entry.FavoriteBooks++
+	}
+	if book.Rating != nil {
+		entry.RatedBooks++
+		entry.totalRating += *book.Rating
+	}
+}
+
+// sortedBreakdown computes the average ratings and orders the entries by book count, then by name
+func sortedBreakdown(entries map[string]*breakdownEntry) []breakdownEntry {
+	result := make([]breakdownEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.RatedBooks > 0 {
+			average := math.Round(entry.totalRating/float64(entry.RatedBooks)*100) / 100
+			entry.AverageRating = &average
+		}
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Books != result[j].Books {
+			return result[i].Books > result[j].Books
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// limitBreakdown keeps the first entries, all of them when limit is 0
+func limitBreakdown(entries []breakdownEntry, limit int) []breakdownEntry {
+	if limit > 0 && len(entries) > limit {
+		return entries[:limit]
+	}
+	return entries
+}
+
+// countBookVariety returns the number of different genres and authors among the finished books
+func countBookVariety(db *gorm.DB, userID uuid.UUID) (int, int) {
+	var books []models.Book
+	if err := db.Select("status", "genres", "authors").
+		Where("user_id = ? AND status = ?", userID, models.StatusFinished).
+		Find(&books).Error; err != nil {
+		sugar.Errorw("Failed to count genres and authors", "userID", userID, "error", err)
+		return 0, 0
+	}
+	return bookVariety(books)
+}
+
+// bookVariety counts the different normalized genres and authors of the finished books
+func bookVariety(books []models.Book) (int, int) {
+	genres := make(map[string]bool)
+	authors := make(map[string]bool)
+	for _, book := range books {
+		if book.Status != models.StatusFinished {
+			continue
+		}
+		for _, genre := range utils.NormalizeGenres(book.Genres) {
+			genres[utils.NormalizeTitle(genre)] = true
+		}
+		for _, author := range book.Authors {
+			if key := utils.NormalizeAuthorName(author); key != "" {
+				authors[key] = true
+			}
+		}
+	}
+	return len(genres), len(authors)
+}
@@ -3,19 +3,35 @@ package controllers
 import (
 	"booksrendezvous-backend/database"
 	"booksrendezvous-backend/models"
+	"errors"
+	"math"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type statstoreturn struct {
-	TotalBooks     int     `json:"totalBooks"`
-	CompletedBooks int     `json:"completedBooks"`
-	ToReadBooks    int     `json:"toReadBooks"`
-	ReadingBooks   int     `json:"readingBooks"`
-	FavoriteBooks  int     `json:"favoriteBooks"`
-	TotalPages     int     `json:"totalPages"`
-	AverageRating  float64 `json:"averageRating"`
+	TotalBooks       int     `json:"totalBooks"`
+	CompletedBooks   int     `json:"completedBooks"`
+	ToReadBooks      int     `json:"toReadBooks"`
+	ReadingBooks     int     `json:"readingBooks"`
+	AbandonedBooks   int     `json:"abandonedBooks"`
+	FavoriteBooks    int     `json:"favoriteBooks"`
+	TotalPages       int     `json:"totalPages"`
+	ListeningMinutes int     `json:"listeningMinutes"`
+	ListeningHours   float64 `json:"listeningHours"`
+	CompletedSeries  int     `json:"completedSeries"`
+	RatedBooks       int     `json:"ratedBooks"`
+	AverageRating    float64 `json:"averageRating"`
+	DistinctGenres   int     `json:"distinctGenres"`
+	DistinctAuthors  int     `json:"distinctAuthors"`
+	CompletedGoals   int     `json:"completedGoals"`
+	CurrentStreak    int     `json:"currentStreak"` // 0 sans lecture hier ni aujourd'hui
+	LongestStreak    int     `json:"longestStreak"`
+	LastReadingDate  string  `json:"lastReadingDate"` // Vide si aucune lecture
 }
 
 func GetStats(c *fiber.Ctx) error {
@@ -37,6 +53,15 @@ func GetStats(c *fiber.Ctx) error {
 		})
 	}
 
+	userstats := loadUserStats(userID)
+
+	// Return user stats as JSON response
+	sugar.Infow("User stats retrieved successfully")
+	return c.JSON(newStatsResponse(userstats))
+}
+
+// loadUserStats returns the stored user stats, computing and saving them on first access
+func loadUserStats(userID uuid.UUID) models.UserStat {
 	var userstats models.UserStat
 	// Query user stats from database using ID
 	database.DB.Where("user_id =?", userID).First(&userstats)
@@ -44,59 +69,78 @@ func GetStats(c *fiber.Ctx) error {
 	// Check if user stats exist
 	if userstats.UserID == "" {
 		// User stats do not exist, compute from scratch
-		userstats = ComputeStatsFromScratch(userID)
-		// Save user stats to database
-		database.DB.Create(&userstats)
-	} else {
-		// User stats exist, return as JSON response
-		sugar.Infow("User stats retrieved successfully")
-		// parse userstats to statstoreturn
-		toreturn := statstoreturn{
-			TotalBooks:     userstats.TotalBooks,
-			CompletedBooks: userstats.CompletedBooks,
-			ToReadBooks:    userstats.ToReadBooks,
-			ReadingBooks:   userstats.ReadingBooks,
-			FavoriteBooks:  userstats.FavoriteBooks,
-			TotalPages:     userstats.TotalPages,
-			AverageRating:  userstats.AverageRating,
+		computed, err := ComputeStatsFromScratch(userID)
+		if err != nil {
+			// Renvoyées sans être enregistrées, pour ne pas figer des séries fausses
+			sugar.Errorw("Failed to compute user stats", "userID", userID, "error", err)
+			return computed
 		}
-
-		return c.JSON(toreturn)
+		userstats = computed
+		// Save user stats to database, unless a concurrent request already did
+		database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&userstats)
 	}
 
-	// Return user stats as JSON response
-	sugar.Infow("User stats retrieved successfully")
-	toreturn := statstoreturn{
-		TotalBooks:     userstats.TotalBooks,
-		CompletedBooks: userstats.CompletedBooks,
-		ToReadBooks:    userstats.ToReadBooks,
-		ReadingBooks:   userstats.ReadingBooks,
-		FavoriteBooks:  userstats.FavoriteBooks,
-		TotalPages:     userstats.TotalPages,
-		AverageRating:  userstats.AverageRating,
+	return userstats
+}
+
+// newStatsResponse converts the stored stats into the API representation
+func newStatsResponse(userstats models.UserStat) statstoreturn {
+	response := statstoreturn{
+		TotalBooks:       userstats.TotalBooks,
+		CompletedBooks:   userstats.CompletedBooks,
+		ToReadBooks:      userstats.ToReadBooks,
+		ReadingBooks:     userstats.ReadingBooks,
+		AbandonedBooks:   userstats.AbandonedBooks,
+		FavoriteBooks:    userstats.FavoriteBooks,
+		TotalPages:       userstats.TotalPages,
+		ListeningMinutes: userstats.ListeningMinutes,
+		ListeningHours:   math.Round(float64(userstats.ListeningMinutes)/60*10) / 10,
+		CompletedSeries:  userstats.CompletedSeries,
+		RatedBooks:       userstats.RatedBooks,
+		AverageRating:    userstats.AverageRating,
+		DistinctGenres:   userstats.DistinctGenres,
+		DistinctAuthors:  userstats.DistinctAuthors,
+		CompletedGoals:   userstats.CompletedGoals,
+		CurrentStreak:    activeStreak(userstats, time.Now()),
+		LongestStreak:    userstats.LongestStreak,
 	}
+	if userstats.LastReadingDate != nil {
+		response.LastReadingDate = dayKey(*userstats.LastReadingDate)
+	}
+	return response
+}
 
-	return c.JSON(toreturn)
+// ComputeStatsFromScratch counts the user stats from all the books in the library
+func ComputeStatsFromScratch(userID uuid.UUID) (models.UserStat, error) {
+	return computeUserStats(database.DB, userID)
 }
 
-func ComputeStatsFromScratch(userID uuid.UUID) models.UserStat {
+// computeUserStats counts the user stats through db, a transaction sees its own book changes
+func computeUserStats(db *gorm.DB, userID uuid.UUID) (models.UserStat, error) {
 	// Query all books from database
 	var books []models.Book
-	database.DB.Where("user_id = ?", userID).Find(&books)
+	db.Where("user_id = ?", userID).Find(&books)
 
 	// Compute total books
 	totalBooks := len(books)
 
 	// Compute total pages/ totalfavoritebooks
 	totalPages := 0
+	listeningMinutes := 0
 	totalFavoriteBooks := 0
 	completedBooks := 0
 	toreadBooks := 0
 	readingBooks := 0
-	totalRating := 0
+	abandonedBooks := 0
+	ratedBooks := 0
+	totalRating := 0.0
 	for _, book := range books {
-		totalPages += book.PageCount
-		totalRating += book.Rating
+		totalPages += printedPages(book)
+		listeningMinutes += bookListeningMinutes(book)
+		if book.Rating != nil {
+			ratedBooks++
+			totalRating += *book.Rating
+		}
 		if book.Favorite {
 			totalFavoriteBooks++
 		}
@@ -109,36 +153,106 @@ func ComputeStatsFromScratch(userID uuid.UUID) models.UserStat {
 		if book.Status == "reading" {
 			readingBooks++
 		}
+		if book.Status == models.StatusAbandoned {
+			abandonedBooks++
+		}
 
 	}
 	var userstats models.UserStat
-	// Compute average rating
-	if totalBooks > 0 {
-		averageRating := float64(totalRating) / float64(totalBooks)
+	// Compute average rating over the rated books only
+	if ratedBooks > 0 {
+		averageRating := totalRating / float64(ratedBooks)
 
 		userstats.AverageRating = averageRating
 	}
+	userstats.RatedBooks = ratedBooks
 
 	userstats.UserID = userID.String()
 	userstats.TotalBooks = totalBooks
 	userstats.TotalPages = totalPages
+	userstats.ListeningMinutes = listeningMinutes
 	userstats.FavoriteBooks = totalFavoriteBooks
 	userstats.CompletedBooks = completedBooks
 	userstats.ToReadBooks = toreadBooks
 	userstats.ReadingBooks = readingBooks
+	userstats.AbandonedBooks = abandonedBooks
+	userstats.CompletedSeries = countCompletedSeries(db, userID)
+	userstats.DistinctGenres, userstats.DistinctAuthors = bookVariety(books)
+	userstats.CompletedGoals = countCompletedGoals(db, userID)
+	var err error
+	userstats.CurrentStreak, userstats.LongestStreak, userstats.LastReadingDate, err = computeStreaks(db, userID)
 
-	return userstats
+	return userstats, err
 }
 
-func OnAddUpdateStats(userID uuid.UUID, book models.Book) {
-	// Query user stats from database using ID
+// RefreshUserStats recomputes the user stats from scratch and saves them
+func RefreshUserStats(userID uuid.UUID) (models.UserStat, error) {
 	var userstats models.UserStat
-	database.DB.Where("user_id =?", userID).First(&userstats)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		userstats, err = refreshUserStats(tx, userID)
+		return err
+	})
+	if err != nil {
+		sugar.Errorw("Failed to save user stats", "userID", userID, "error", err)
+	}
+	return userstats, err
+}
+
+// refreshUserStats recomputes and saves the user stats inside the transaction, the stats row stays locked until it ends
+func refreshUserStats(tx *gorm.DB, userID uuid.UUID) (models.UserStat, error) {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return userstats, err
+	}
+	if userstats, err = computeUserStats(tx, userID); err != nil {
+		return userstats, err
+	}
+	return userstats, tx.Save(&userstats).Error
+}
+
+// lockUserStats reads the user stats row with a row lock held until the end of the transaction.
+// A missing row is computed from scratch and created, created is then true and the stats
+// already include the changes made earlier in the transaction.
+func lockUserStats(tx *gorm.DB, userID uuid.UUID) (models.UserStat, bool, error) {
+	var userstats models.UserStat
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&userstats).Error
+	if err == nil {
+		return userstats, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return userstats, false, err
+	}
+
+	if userstats, err = computeUserStats(tx, userID); err != nil {
+		return userstats, false, err
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&userstats)
+	if result.Error != nil {
+		return userstats, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return userstats, true, nil
+	}
+
+	// Créée entre-temps par une requête concurrente, sans nos modifications
+	userstats = models.UserStat{}
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&userstats).Error
+	return userstats, false, err
+}
+
+// OnAddUpdateStats adds a book to the user stats, in the transaction that created the book
+func OnAddUpdateStats(tx *gorm.DB, userID uuid.UUID, book models.Book) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
 
 	// Update user stats
 	userstats.TotalBooks++
-	userstats.TotalPages += book.PageCount
-	userstats.AverageRating = (float64(userstats.TotalBooks-1)*userstats.AverageRating + float64(book.Rating)) / float64(userstats.TotalBooks)
+	userstats.TotalPages += printedPages(book)
+	userstats.ListeningMinutes += bookListeningMinutes(book)
+	applyRatingChange(&userstats, nil, book.Rating)
 	if book.Favorite {
 		userstats.FavoriteBooks++
 	}
@@ -151,20 +265,32 @@ func OnAddUpdateStats(userID uuid.UUID, book models.Book) {
 	if book.Status == "reading" {
 		userstats.ReadingBooks++
 	}
+	if book.Status == models.StatusAbandoned {
+		userstats.AbandonedBooks++
+	}
+	if book.SeriesID != nil {
+		userstats.CompletedSeries = countCompletedSeries(tx, userID)
+	}
+	if book.Status == models.StatusFinished {
+		userstats.DistinctGenres, userstats.DistinctAuthors = countBookVariety(tx, userID)
+	}
 
 	// Save updated user stats to database
-	database.DB.Save(&userstats)
+	return tx.Save(&userstats).Error
 }
 
-func OnDeleteUpdateStats(userID uuid.UUID, book models.Book) {
-	// Query user stats from database using ID
-	var userstats models.UserStat
-	database.DB.Where("user_id =?", userID).First(&userstats)
+// OnDeleteUpdateStats removes a book from the user stats, in the transaction that deleted the book
+func OnDeleteUpdateStats(tx *gorm.DB, userID uuid.UUID, book models.Book) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
 
 	// Update user stats
 	userstats.TotalBooks--
-	userstats.TotalPages -= book.PageCount
-	userstats.AverageRating = (float64(userstats.TotalBooks+1)*userstats.AverageRating - float64(book.Rating)) / float64(userstats.TotalBooks)
+	userstats.TotalPages -= printedPages(book)
+	userstats.ListeningMinutes -= bookListeningMinutes(book)
+	applyRatingChange(&userstats, book.Rating, nil)
 	if book.Favorite {
 		userstats.FavoriteBooks--
 	}
@@ -177,15 +303,27 @@ func OnDeleteUpdateStats(userID uuid.UUID, book models.Book) {
 	if book.Status == "reading" {
 		userstats.ReadingBooks--
 	}
+	if book.Status == models.StatusAbandoned {
+		userstats.AbandonedBooks--
+	}
+	if book.SeriesID != nil {
+		userstats.CompletedSeries = countCompletedSeries(tx, userID)
+	}
+	if book.Status == models.StatusFinished {
+		userstats.DistinctGenres, userstats.DistinctAuthors = countBookVariety(tx, userID)
+	}
 
 	// Save updated user stats to database
-	database.DB.Save(&userstats)
+	return tx.Save(&userstats).Error
 }
 
-func OnChangeUpdateStats(userID uuid.UUID, newbook models.Book, oldbook models.Book) {
-	// Query user stats from database using ID
-	var userstats models.UserStat
-	database.DB.Where("user_id =?", userID).First(&userstats)
+// OnChangeUpdateStats applies the difference between two states of a book to the user stats,
+// in the transaction that saved the new state
+func OnChangeUpdateStats(tx *gorm.DB, userID uuid.UUID, newbook models.Book, oldbook models.Book) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
 
 	// Action pour le changement de statut
 	if newbook.Status != oldbook.Status {
@@ -195,6 +333,8 @@ func OnChangeUpdateStats(userID uuid.UUID, newbook models.Book, oldbook models.B
 			userstats.ToReadBooks++
 		} else if newbook.Status == "reading" {
 			userstats.ReadingBooks++
+		} else if newbook.Status == models.StatusAbandoned {
+			userstats.AbandonedBooks++
 		}
 
 		if oldbook.Status == "finished" {
@@ -203,6 +343,8 @@ func OnChangeUpdateStats(userID uuid.UUID, newbook models.Book, oldbook models.B
 			userstats.ToReadBooks--
 		} else if oldbook.Status == "reading" {
 			userstats.ReadingBooks--
+		} else if oldbook.Status == models.StatusAbandoned {
+			userstats.AbandonedBooks--
 		}
 	}
 	if newbook.Favorite != oldbook.Favorite {
@@ -213,10 +355,128 @@ func OnChangeUpdateStats(userID uuid.UUID, newbook models.Book, oldbook models.B
 		}
 	}
 
-	if newbook.Rating != oldbook.Rating {
-		userstats.AverageRating = (float64(userstats.TotalBooks)*userstats.AverageRating - float64(oldbook.Rating) + float64(newbook.Rating)) / float64(userstats.TotalBooks)
+	userstats.TotalPages += printedPages(newbook) - printedPages(oldbook)
+	userstats.ListeningMinutes += bookListeningMinutes(newbook) - bookListeningMinutes(oldbook)
+
+	applyRatingChange(&userstats, oldbook.Rating, newbook.Rating)
+
+	// La complétion des séries est recomptée dès qu'un livre de série change
+	if newbook.SeriesID != nil || oldbook.SeriesID != nil {
+		userstats.CompletedSeries = countCompletedSeries(tx, userID)
+	}
+	// Genres et auteurs différents recomptés dès qu'un livre lu change
+	if newbook.Status == models.StatusFinished || oldbook.Status == models.StatusFinished {
+		userstats.DistinctGenres, userstats.DistinctAuthors = countBookVariety(tx, userID)
 	}
 
 	// Sauvegarder les stats mises à jour
-	database.DB.Save(&userstats)
+	return tx.Save(&userstats).Error
+}
+
+// ReconcileUserStats compares the stored stats of every user with a count from scratch and repairs
+// the drifted ones, returning how many were repaired
+func ReconcileUserStats() (int, error) {
+	var userIDs []string
+	if err := database.DB.Model(&models.UserStat{}).Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	repaired := 0
+	for _, id := range userIDs {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			stored, created, err := lockUserStats(tx, userID)
+			if err != nil || created {
+				return err
+			}
+			computed, err := computeUserStats(tx, userID)
+			if err != nil {
+				return err
+			}
+			if statsDrift(stored, computed) {
+				sugar.Warnw("User stats drifted, repairing", "userID", userID, "stored", newStatsResponse(stored), "computed", newStatsResponse(computed))
+				repaired++
+				return tx.Save(&computed).Error
+			}
+			return nil
+		})
+		if err != nil {
+			sugar.Errorw("Failed to reconcile user stats", "userID", userID, "error", err)
+		}
+	}
+	return repaired, nil
+}
+
+// StartStatsReconciliation reconciles the user stats every intervalHours hours in the background, 0 disables it
+func StartStatsReconciliation(intervalHours int) {
+	if intervalHours <= 0 {
+		sugar.Info("Stats reconciliation disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalHours) * time.Hour)
+		defer ticker.Stop()
+		for {
+			repaired, err := ReconcileUserStats()
+			if err != nil {
+				sugar.Errorw("Failed to reconcile user stats", "error", err)
+			} else if repaired > 0 {
+				sugar.Infow("User stats reconciled", "repaired", repaired)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// statsDrift reports whether stored stats differ from the computed ones, the average to its stored precision
+func statsDrift(stored models.UserStat, computed models.UserStat) bool {
+	stored.AverageRating = roundRating(stored.AverageRating)
+	computed.AverageRating = roundRating(computed.AverageRating)
+	return newStatsResponse(stored) != newStatsResponse(computed)
+}
+
+// roundRating rounds an average rating to the two decimals of the user_stats column
+func roundRating(rating float64) float64 {
+	return math.Round(rating*100) / 100
+}
+
+// printedPages returns the pages a book adds to TotalPages, audiobooks count in listening time instead
+func printedPages(book models.Book) int {
+	if book.Format == models.FormatAudiobook {
+		return 0
+	}
+	return book.PageCount
+}
+
+// bookListeningMinutes returns the listening time a book adds to the stats, only for audiobooks
+func bookListeningMinutes(book models.Book) int {
+	if book.Format != models.FormatAudiobook {
+		return 0
+	}
+	return book.DurationMinutes
+}
+
+// applyRatingChange updates the average rating when a book rating goes from oldRating to newRating,
+// nil meaning unrated. Only rated books count in the average.
+func applyRatingChange(userstats *models.UserStat, oldRating, newRating *float64) {
+	total := userstats.AverageRating * float64(userstats.RatedBooks)
+	if oldRating != nil {
+		total -= *oldRating
+		userstats.RatedBooks--
+	}
+	if newRating != nil {
+		total += *newRating
+		userstats.RatedBooks++
+	}
+
+	if userstats.RatedBooks <= 0 {
+		userstats.RatedBooks = 0
+		userstats.AverageRating = 0
+		return
+	}
+	userstats.AverageRating = total / float64(userstats.RatedBooks)
 }
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Jours de lecture : sessions terminées, à leur date, et progressions saisies sur un livre, le jour de la saisie.
+// Les sessions ont leur propre action d'historique, et une hausse due à un changement d'unité n'est pas une lecture.
+const readingDaysQuery = `SELECT day FROM (
+	SELECT date AS day FROM reading_sessions
+	WHERE user_id = ? AND NOT (started_at IS NOT NULL AND ended_at IS NULL)
+	UNION
+	SELECT created_at::date AS day FROM book_histories
+	WHERE user_id = ? AND action = ? AND changes->'progressUnit' IS NULL
+	AND (changes->'progress'->>'new')::int > (changes->'progress'->>'old')::int
+) reading_days ORDER BY day`
+
+// dayKey returns the calendar day of a date, used to compare reading days
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// previousDayKey returns the day before the given day key
+func previousDayKey(key string) string {
+	day, _ := time.Parse("2006-01-02", key)
+	return dayKey(day.AddDate(0, 0, -1))
+}
+
+// computeStreaks returns the current streak, ending on the last reading day, the longest streak
+// and the last reading day of the user, nil when the user never read
+func computeStreaks(db *gorm.DB, userID uuid.UUID) (int, int, *time.Time, error) {
+	var days []struct{ Day time.Time }
+	if err := db.Raw(readingDaysQuery, userID, userID, models.HistoryUpdate).Scan(&days).Error; err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to compute reading streaks: %w", err)
+	}
+
+	readingDays := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		readingDays = append(readingDays, day.Day)
+	}
+	current, longest, last := foldStreaks(readingDays)
+	return current, longest, last, nil
+}
+
+// foldStreaks computes the streaks over reading days sorted by date, a day listed twice counting once
+func foldStreaks(days []time.Time) (int, int, *time.Time) {
+	if len(days) == 0 {
+		return 0, 0, nil
+	}
+
+	current, longest := 0, 0
+	previous := ""
+	for _, day := range days {
+		key := dayKey(day)
+		switch {
+		case key == previous:
+			continue
+		case previous != "" && previousDayKey(key) == previous:
+			current++
+		default:
+			current = 1
+		}
+		if current > longest {
+			longest = current
+		}
+		previous = key
+	}
+	last := days[len(days)-1]
+	return current, longest, &last
+}
+
+// applyReadingDay extends the streaks of the locked user stats with a reading day. A day before
+// the last reading day can join or split past streaks, they are then computed again.
+func applyReadingDay(tx *gorm.DB, userstats *models.UserStat, userID uuid.UUID, day time.Time) error {
+	if extendStreaks(userstats, day) {
+		return nil
+	}
+	current, longest, last, err := computeStreaks(tx, userID)
+	if err != nil {
+		return err
+	}
+	userstats.CurrentStreak, userstats.LongestStreak, userstats.LastReadingDate = current, longest, last
+	return nil
+}
+
+// extendStreaks adds a reading day on or after the last one to the streaks, and reports false
+// for an earlier day, whose streaks have to be computed again
+func extendStreaks(userstats *models.UserStat, day time.Time) bool {
+	key := dayKey(day)
+	if userstats.LastReadingDate != nil {
+		last := dayKey(*userstats.LastReadingDate)
+		if key == last {
+			return true
+		}
+		if key < last {
+			return false
+		}
+	}
+
+	if userstats.LastReadingDate != nil && previousDayKey(key) == dayKey(*userstats.LastReadingDate) {
+		userstats.CurrentStreak++
+	} else {
+		userstats.CurrentStreak = 1
+	}
+	if userstats.CurrentStreak > userstats.LongestStreak {
+		userstats.LongestStreak = userstats.CurrentStreak
+	}
+	readingDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	userstats.LastReadingDate = &readingDay
+	return true
+}
+
+// OnReadingDayUpdateStats records a reading day in the streaks, in the transaction that saved the session
+func OnReadingDayUpdateStats(tx *gorm.DB, userID uuid.UUID, day time.Time) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
+	if err := applyReadingDay(tx, &userstats, userID, day); err != nil {
+		return err
+	}
+	return tx.Save(&userstats).Error
+}
+
+// readingProgressed reports whether an edit of a book counts as a reading day: its progress increased
+// in the same unit, a conversion to another unit being no reading
+func readingProgressed(oldbook models.Book, newbook models.Book) bool {
+	return newbook.Progress > oldbook.Progress && newbook.ProgressUnit == oldbook.ProgressUnit
+}
+
+// refreshStreaks computes the streaks again once a reading session is removed
+func refreshStreaks(tx *gorm.DB, userID uuid.UUID) error {
+	userstats, created, err := lockUserStats(tx, userID)
+	if err != nil || created {
+		return err
+	}
+	if userstats.CurrentStreak, userstats.LongestStreak, userstats.LastReadingDate, err = computeStreaks(tx, userID); err != nil {
+		return err
+	}
+	return tx.Save(&userstats).Error
+}
+
+// activeStreak returns the current streak as long as it is not broken, that is when the user read today or yesterday
+func activeStreak(userstats models.UserStat, now time.Time) int {
+	if userstats.LastReadingDate == nil {
+		return 0
+	}
+	last := dayKey(*userstats.LastReadingDate)
+	if today := dayKey(now); last != today && last != previousDayKey(today) {
+		return 0
+	}
+	return userstats.CurrentStreak
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/models"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return day
+}
+
+func dates(values ...string) []time.Time {
+	days := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		days = append(days, date(value))
+	}
+	return days
+}
+
+func TestPreviousDayKey(t *testing.T) {
+	cases := map[string]string{
+		"2024-05-10": "2024-05-09",
+		"2024-05-01": "2024-04-30",
+		"2024-03-01": "2024-02-29",
+		"2023-03-01": "2023-02-28",
+		"2024-01-01": "2023-12-31",
+	}
+	for key, expected := range cases {
+		if got := previousDayKey(key); got != expected {
+			t.Errorf("previousDayKey(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+}
+
+func TestFoldStreaks(t *testing.T) {
+	cases := []struct {
+		name    string
+		days    []time.Time
+		current int
+		longest int
+		last    string
+	}{
+		{"no reading day", nil, 0, 0, ""},
+		{"single day", dates("2024-05-10"), 1, 1, "2024-05-10"},
+		{"consecutive days", dates("2024-05-08", "2024-05-09", "2024-05-10"), 3, 3, "2024-05-10"},
+		{"gap resets the current streak", dates("2024-05-01", "2024-05-02", "2024-05-03", "2024-05-05", "2024-05-06"), 2, 3, "2024-05-06"},
+		{"day listed twice counts once", dates("2024-05-09", "2024-05-09", "2024-05-10"), 2, 2, "2024-05-10"},
+		{"streak across months", dates("2024-04-29", "2024-04-30", "2024-05-01"), 3, 3, "2024-05-01"},
+		{"longest streak ending last", dates("2024-05-01", "2024-05-03", "2024-05-04"), 2, 2, "2024-05-04"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			current, longest, last := foldStreaks(tc.days)
+			if current != tc.current || longest != tc.longest {
+				t.Errorf("foldStreaks = %d, %d, expected %d, %d", current, longest, tc.current, tc.longest)
+			}
+			switch {
+			case tc.last == "" && last != nil:
+				t.Errorf("foldStreaks last day = %v, expected none", *last)
+			case tc.last != "" && (last == nil || dayKey(*last) != tc.last):
+				t.Errorf("foldStreaks last day = %v, expected %s", last, tc.last)
+			}
+		})
+	}
+}
+
+func TestExtendStreaks(t *testing.T) {
+	last := date("2024-05-10")
+	cases := []struct {
+		name     string
+		day      string
+		extended bool
+		current  int
+		longest  int
+		last     string
+	}{
+		{"same day", "2024-05-10", true, 3, 5, "2024-05-10"},
+		{"next day", "2024-05-11", true, 4, 5, "2024-05-11"},
+		{"missed day", "2024-05-12", true, 1, 5, "2024-05-12"},
+		{"day before the last reading day", "2024-05-08", false, 3, 5, "2024-05-10"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userstats := models.UserStat{CurrentStreak: 3, LongestStreak: 5, LastReadingDate: &last}
+			if got := extendStreaks(&userstats, date(tc.day)); got != tc.extended {
+				t.Fatalf("extendStreaks(%s) = %v, expected %v", tc.day, got, tc.extended)
+			}
+			if userstats.CurrentStreak != tc.current || userstats.LongestStreak != tc.longest || dayKey(*userstats.LastReadingDate) != tc.last {
+				t.Errorf("extendStreaks(%s) gave %d, %d, %s, expected %d, %d, %s", tc.day, userstats.CurrentStreak, userstats.LongestStreak,
+					dayKey(*userstats.LastReadingDate), tc.current, tc.longest, tc.last)
+			}
+		})
+	}
+
+	t.Run("first reading day", func(t *testing.T) {
+		var userstats models.UserStat
+		if !extendStreaks(&userstats, date("2024-05-10")) {
+			t.Fatal("extendStreaks of a first reading day asked for a recompute")
+		}
+		if userstats.CurrentStreak != 1 || userstats.LongestStreak != 1 {
+			t.Errorf("extendStreaks of a first reading day gave %d, %d, expected 1, 1", userstats.CurrentStreak, userstats.LongestStreak)
+		}
+	})
+
+	t.Run("new longest streak", func(t *testing.T) {
+		userstats := models.UserStat{CurrentStreak: 5, LongestStreak: 5, LastReadingDate: &last}
+		extendStreaks(&userstats, date("2024-05-11"))
+		if userstats.LongestStreak != 6 {
+			t.Errorf("extendStreaks longest streak = %d, expected 6", userstats.LongestStreak)
+		}
+	})
+}
+
+func TestActiveStreak(t *testing.T) {
+	last := date("2024-05-10")
+	userstats := models.UserStat{CurrentStreak: 4, LongestStreak: 4, LastReadingDate: &last}
+	cases := map[string]int{
+		"2024-05-10": 4,
+		"2024-05-11": 4,
+		"2024-05-12": 0,
+	}
+	for now, expected := range cases {
+		if got := activeStreak(userstats, date(now).Add(20*time.Hour)); got != expected {
+			t.Errorf("activeStreak on %s = %d, expected %d", now, got, expected)
+		}
+	}
+	if got := activeStreak(models.UserStat{}, last); got != 0 {
+		t.Errorf("activeStreak without reading day = %d, expected 0", got)
+	}
+}
+
+func TestReadingProgressed(t *testing.T) {
+	cases := []struct {
+		name     string
+		old      models.Book
+		new      models.Book
+		progress bool
+	}{
+		{"progress increased", models.Book{Progress: 10, ProgressUnit: "pages"}, models.Book{Progress: 25, ProgressUnit: "pages"}, true},
+		{"progress unchanged", models.Book{Progress: 10, ProgressUnit: "pages"}, models.Book{Progress: 10, ProgressUnit: "pages"}, false},
+		{"progress decreased", models.Book{Progress: 10, ProgressUnit: "pages"}, models.Book{Progress: 5, ProgressUnit: "pages"}, false},
+		{"unit changed", models.Book{Progress: 10, ProgressUnit: "percent"}, models.Book{Progress: 120, ProgressUnit: "pages"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := readingProgressed(tc.old, tc.new); got != tc.progress {
+				t.Errorf("readingProgressed = %v, expected %v", got, tc.progress)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Regroupements possibles de la chronologie, noms des unités de date_trunc
+const (
+	timelineDay   = "day"
+	timelineWeek  = "week"
+	timelineMonth = "month"
+	timelineYear  = "year"
+)
+
+// Au-delà, la période demandée est trop fine pour l'intervalle
+const maxTimelinePoints = 1000
+
+type timelinePoint struct {
+	Period        string   `json:"period"` // Premier jour de la période
+	BooksFinished int      `json:"booksFinished"`
+	PagesRead     int      `json:"pagesRead"`
+	MinutesRead   int      `json:"minutesRead"`
+	AverageRating *float64 `json:"averageRating"` // Moyenne des livres terminés et notés, nil sans note
+}
+
+// Ligne d'agrégation SQL, une par période non vide
+type timelineRow struct {
+	Bucket        time.Time
+	Books         int
+	AverageRating *float64
+	Pages         int
+	Minutes       int
+}
+
+// GetStatsTimeline returns the books finished, pages read and average rating grouped by period
+// (day, week, month or year) between two dates, every period of the range included
+func GetStatsTimeline(c *fiber.Ctx) error {
+	sugar.Info("Received a stats timeline request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	period := c.Query("period", timelineMonth)
+	if period != timelineDay && period != timelineWeek && period != timelineMonth && period != timelineYear {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Period must be day, week, month or year",
+		})
+	}
+
+	// Par défaut les 30 derniers jours au jour le jour, la dernière année sinon
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	if period == timelineDay {
+		from = to.AddDate(0, 0, -30)
+	}
+	if value, err := parseBookDate(c.Query("from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid from date",
+		})
+	} else if value != nil {
+		from = *value
+	}
+	if value, err := parseBookDate(c.Query("to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid to date",
+		})
+	} else if value != nil {
+		to = *value
+	}
+	if to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The to date cannot be before the from date",
+		})
+	}
+
+	points, index := timelineBuckets(period, from, to)
+	if points == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Range too long for this period",
+		})
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	// Livres terminés et leur note moyenne, par date de fin de lecture
+	var finished []timelineRow
+	if err := database.DB.Model(&models.Book{}).
+		Select("date_trunc(?, end_date::date::timestamp) AS bucket, COUNT(*) AS books, AVG(rating) AS average_rating", period).
+		Where("user_id = ? AND status = ? AND end_date IS NOT NULL", userID, models.StatusFinished).
+		Where("end_date::date BETWEEN ? AND ?", fromDate, toDate).
+		Group("bucket").
+		Scan(&finished).Error; err != nil {
+		sugar.Errorw("Failed to get finished books timeline", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get stats timeline",
+		})
+	}
+
+	// Pages et minutes lues, par jour des sessions terminées
+	var read []timelineRow
+	if err := database.DB.Model(&models.ReadingSession{}).
+		Select("date_trunc(?, date::timestamp) AS bucket, SUM(pages) AS pages, SUM(minutes) AS minutes", period).
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, fromDate, toDate).
+		Where("NOT (started_at IS NOT NULL AND ended_at IS NULL)").
+		Where(activeBookIDs("book_id")).
+		Group("bucket").
+		Scan(&read).Error; err != nil {
+		sugar.Errorw("Failed to get reading timeline", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get stats timeline",
+		})
+	}
+
+	for _, row := range finished {
+		if i, found := index[row.Bucket.Format("2006-01-02")]; found {
+			points[i].BooksFinished = row.Books
+			if row.AverageRating != nil {
+				average := math.Round(*row.AverageRating*100) / 100
+				points[i].AverageRating = &average
+			}
+		}
+	}
+	for _, row := range read {
+		if i, found := index[row.Bucket.Format("2006-01-02")]; found {
+			points[i].PagesRead = row.Pages
+			points[i].MinutesRead = row.Minutes
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"period": period,
+		"from":   fromDate,
+		"to":     toDate,
+		"points": points,
+	})
+}
+
+// timelineBuckets lists the empty periods covering the range and indexes them by their first day,
+// nil when there would be more than maxTimelinePoints periods
+func timelineBuckets(period string, from time.Time, to time.Time) ([]timelinePoint, map[string]int) {
+	points := []timelinePoint{}
+	index := make(map[string]int)
+	last := truncatePeriod(period, to)
+	for bucket := truncatePeriod(period, from); !bucket.After(last); bucket = nextPeriod(period, bucket) {
+		if len(points) == maxTimelinePoints {
+			return nil, nil
+		}
+		key := bucket.Format("2006-01-02")
+		index[key] = len(points)
+		points = append(points, timelinePoint{Period: key})
+	}
+	return points, index
+}
+
+// truncatePeriod returns the first day of the period containing t, weeks starting on Monday like date_trunc
+func truncatePeriod(period string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case timelineWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case timelineMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case timelineYear:
+		return time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod returns the first day of the period following the one starting at t
+func nextPeriod(period string, t time.Time) time.Time {
+	switch period {
+	case timelineWeek:
+		return t.AddDate(0, 0, 7)
+	case timelineMonth:
+		return t.AddDate(0, 1, 0)
+	case timelineYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
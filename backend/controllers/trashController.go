@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"booksrendezvous-backend/database"
+	"booksrendezvous-backend/models"
+	"booksrendezvous-backend/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Fréquence de la purge automatique de la corbeille
+const trashPurgeInterval = 24 * time.Hour
+
+// activeBookIDs restricts a book_id column to the books that are not in the trash,
+// for the tables that reference books without going through models.Book
+func activeBookIDs(column string) string {
+	return column + " IN (SELECT id FROM books WHERE deleted_at IS NULL)"
+}
+
+// findTrashedBook loads a book of the trash and checks that it belongs to the user
+func findTrashedBook(userID uuid.UUID, bookID string) (models.Book, error) {
+	var book models.Book
+	if err := database.DB.Unscoped().First(&book, "id = ? AND deleted_at IS NOT NULL", bookID).Error; err != nil {
+		sugar.Errorw("Trashed book not found", "bookID", bookID, "error", err)
+		return book, errBookNotFound
+	}
+
+	if book.UserID != userID.String() {
+		sugar.Errorw("Unauthorized trash access attempt",
+			"userID", userID,
+			"bookUserID", book.UserID,
+		)
+		return book, errBookForbidden
+	}
+
+	return book, nil
+}
+
+// GetTrash returns the deleted books of the user, most recently deleted first
+func GetTrash(c *fiber.Ctx) error {
+	sugar.Info("Received a Get Trash request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	books := []models.Book{}
+	if err := database.DB.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&books).Error; err != nil {
+		sugar.Errorw("Failed to get trash", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get trash",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"books":         books,
+		"retentionDays": config.TrashRetentionDays, // 0 = pas de purge automatique
+	})
+}
+
+// RestoreBook takes a book out of the trash and gives its contribution back to the stats
+func RestoreBook(c *fiber.Ctx) error {
+	sugar.Info("Received a Restore Book request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findTrashedBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Le même ISBN a pu être ajouté de nouveau entre-temps
+	if existing, found := findBookByISBN(userID, book.ISBN13); found {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A book with the same ISBN is already in library",
+			"book":  existing,
+		})
+	}
+
+	trashed := book
+	book.DeletedAt = gorm.DeletedAt{}
+	book.Version++
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Book{}).Where("id = ?", book.ID).Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    book.Version,
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordBookHistory(tx, models.HistoryRestore, &trashed, book); err != nil {
+			return err
+		}
+		return OnAddUpdateStats(tx, userID, book)
+	})
+	if err != nil {
+		sugar.Errorw("Failed to restore book", "bookID", book.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to restore book",
+		})
+	}
+
+	service := services.NewAchievementService(database.DB)
+	if err := service.CheckAchievements(uiidStr); err != nil {
+		sugar.Errorw("Failed to check achievements", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check achievements",
+		})
+	}
+
+	sugar.Infow("Book restored successfully", "bookID", book.ID)
+	return c.JSON(fiber.Map{
+		"message": "Book restored successfully",
+		"book":    book,
+	})
+}
+
+// PurgeBook permanently deletes a book of the trash, with its notes and reading sessions
+func PurgeBook(c *fiber.Ctx) error {
+	sugar.Info("Received a Purge Book request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	book, err := findTrashedBook(userID, c.Params("id"))
+	if err != nil {
+		return bookLookupError(c, err)
+	}
+
+	// Les stats ont déjà été mises à jour lors de la mise à la corbeille
+	if err := database.DB.Unscoped().Delete(&book).Error; err != nil {
+		sugar.Errorw("Failed to purge book", "bookID", book.ID, "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to purge book",
+		})
+	}
+
+	sugar.Infow("Book purged successfully", "bookID", book.ID)
+	return c.JSON(fiber.Map{
+		"message": "Book permanently deleted",
+	})
+}
+
+// EmptyTrash permanently deletes every book of the trash
+func EmptyTrash(c *fiber.Ctx) error {
+	sugar.Info("Received an Empty Trash request")
+
+	uiidStr, ok := CheckAuth(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	userID, err := uuid.Parse(uiidStr)
+	if err != nil {
+		sugar.Errorw("Failed to parse uuid", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse uuid",
+		})
+	}
+
+	result := database.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Delete(&models.Book{})
+	if result.Error != nil {
+		sugar.Errorw("Failed to empty trash", "error", result.Error)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to empty trash",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Trash emptied successfully",
+		"purged":  result.RowsAffected,
+	})
+}
+
+// PurgeExpiredTrash permanently deletes the books trashed for longer than the retention period
+func PurgeExpiredTrash(retentionDays int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	result := database.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Book{})
+	return result.RowsAffected, result.Error
+}
+
+// StartTrashPurge purges the expired trash now and then once a day, unless the retention is 0
+func StartTrashPurge(retentionDays int) {
+	if retentionDays <= 0 {
+		sugar.Info("Automatic trash purge disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeExpiredTrash(retentionDays)
+			if err != nil {
+				sugar.Errorw("Failed to purge expired trash", "error", err)
+			} else if purged > 0 {
+				sugar.Infow("Expired trash purged", "books", purged, "retentionDays", retentionDays)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -32,13 +32,65 @@ func ConnectDB(config *utils.Config, sugar *zap.SugaredLogger) (*gorm.DB, error)
 	}
 
 	DB = db
+
+	// Migrations de données à appliquer avant AutoMigrate
+	ratingsMigrated, err := migrateBookRatings(db)
+	if err != nil {
+		sugar.Errorw("Failed to migrate book ratings", "error", err)
+		return nil, err
+	}
+
 	db.AutoMigrate(&models.User{})
+	db.AutoMigrate(&models.Series{})
 	db.AutoMigrate(&models.Book{})
 	db.AutoMigrate(&models.Publicusers{})
 	db.AutoMigrate(&models.UserStat{})
 	db.AutoMigrate(&models.Achievement{})
 	db.AutoMigrate(&models.UserAchievement{})
 	db.AutoMigrate(&models.PasswordResetToken{})
+	db.AutoMigrate(&models.ReadingSession{})
+	db.AutoMigrate(&models.ImportJob{})
+	db.AutoMigrate(&models.MetadataCache{})
+	db.AutoMigrate(&models.Shelf{})
+	db.AutoMigrate(&models.ShelfBook{})
+	db.AutoMigrate(&models.BookNote{})
+	db.AutoMigrate(&models.BookHistory{})
+	db.AutoMigrate(&models.Loan{})
+	db.AutoMigrate(&models.ReadingGoal{})
+
+	// L'historique d'un livre est conservé après sa purge, la clé étrangère créée auparavant est supprimée
+	if err := db.Exec("ALTER TABLE book_histories DROP CONSTRAINT IF EXISTS fk_book_histories_book").Error; err != nil {
+		sugar.Errorw("Failed to drop constraint", "constraint", "fk_book_histories_book", "error", err)
+	}
+
+	// Un ISBN ne peut apparaître qu'une fois dans la bibliothèque d'un utilisateur, hors corbeille
+	if err := db.Exec("DROP INDEX IF EXISTS idx_books_user_isbn13").Error; err != nil {
+		sugar.Errorw("Failed to drop index", "index", "idx_books_user_isbn13", "error", err)
+	}
+	createUniqueIndex(db, sugar, "idx_books_user_isbn13_active", "books (user_id, isbn13) WHERE isbn13 <> '' AND deleted_at IS NULL")
+
+	// Un livre n'a qu'un prêt en cours à la fois
+	createUniqueIndex(db, sugar, "idx_loans_open_book", "loans (book_id) WHERE returned_at IS NULL")
+
+	// Une seule session en cours par livre
+	createUniqueIndex(db, sugar, "idx_reading_sessions_running_book", "reading_sessions (book_id) WHERE started_at IS NOT NULL AND ended_at IS NULL")
+
+	// Une note importée n'est enregistrée qu'une fois, même si le fichier est réimporté
+	createUniqueIndex(db, sugar, "idx_book_notes_user_fingerprint", "book_notes (user_id, fingerprint) WHERE fingerprint <> ''")
+
+	// Les moyennes existantes comptaient les livres non notés comme des 0
+	if ratingsMigrated {
+		if err := refreshRatingStats(db); err != nil {
+			sugar.Errorw("Failed to refresh rating stats", "error", err)
+		}
+	}
 
 	return db, nil
 }
+
+// createUniqueIndex creates a partial unique index, existing duplicates make it fail and are only logged
+func createUniqueIndex(db *gorm.DB, sugar *zap.SugaredLogger, name string, definition string) {
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + name + " ON " + definition).Error; err != nil {
+		sugar.Errorw("Failed to create unique index", "index", name, "error", err)
+	}
+}
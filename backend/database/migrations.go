@@ -0,0 +1,45 @@
+// database/migrations.go
+
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrateBookRatings converts the integer ratings into half-star decimals and reports whether it did.
+// Un ancien 0 signifiait "non noté" et devient NULL pour ne plus fausser la moyenne.
+func migrateBookRatings(db *gorm.DB) (bool, error) {
+	// Une erreur arrête le démarrage : AutoMigrate convertirait sinon les anciens 0 en notes 0.0
+	var dataType string
+	if err := db.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'books' AND column_name = 'rating'`).
+		Scan(&dataType).Error; err != nil {
+		return false, err
+	}
+	if dataType != "integer" {
+		// Table neuve ou déjà migrée
+		return false, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"ALTER TABLE books DROP CONSTRAINT IF EXISTS chk_books_rating",
+			"ALTER TABLE books ALTER COLUMN rating DROP NOT NULL",
+			"ALTER TABLE books ALTER COLUMN rating TYPE decimal(2,1) USING NULLIF(rating, 0)::decimal(2,1)",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err == nil, err
+}
+
+// refreshRatingStats recomputes the rated books count and average rating of every user
+func refreshRatingStats(db *gorm.DB) error {
+	return db.Exec(`UPDATE user_stats SET
+		rated_books = (SELECT COUNT(*) FROM books WHERE books.user_id = user_stats.user_id AND books.rating IS NOT NULL),
+		average_rating = COALESCE((SELECT AVG(books.rating) FROM books WHERE books.user_id = user_stats.user_id), 0)`).Error
+}
@@ -45,16 +45,25 @@ func main() {
 	}
 	sugar.Info("Successfully connected to database")
 
+	// Purge automatique des livres restés trop longtemps dans la corbeille
+	controllers.StartTrashPurge(config.TrashRetentionDays)
+
+	// Rappels par email des prêts en retard
+	controllers.StartLoanReminders(config.LoanReminderDays)
+
+	// Correction périodique des stats utilisateur qui auraient dérivé
+	controllers.StartStatsReconciliation(config.StatsReconcileHours)
+
 	// Initialize Fiber app
 	app := fiber.New()
 
 	// Adding CORS middleware with specific origin
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     config.FrontendURL,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Requested-With, Accept, credentials",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Requested-With, Accept, credentials, If-Match",
 		AllowCredentials: true,
-		ExposeHeaders:    "Set-Cookie",
+		ExposeHeaders:    "Set-Cookie, ETag",
 	}))
 
 	app.Use(func(c *fiber.Ctx) error {
@@ -1,13 +1,40 @@
 package models
 
-import "github.com/lib/pq"
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Statuts de lecture possibles pour un livre
+const (
+	StatusToRead    = "to-read"
+	StatusReading   = "reading"
+	StatusFinished  = "finished"
+	StatusAbandoned = "abandoned"
+)
+
+// Formats possibles pour un livre
+const (
+	FormatPaperback = "paperback"
+	FormatHardcover = "hardcover"
+	FormatEbook     = "ebook"
+	FormatAudiobook = "audiobook"
+)
+
+// Unités dans lesquelles la progression est exprimée
+const (
+	ProgressPages   = "pages"
+	ProgressPercent = "percent"
+	ProgressMinutes = "minutes" // Livres audio
+)
 
 type Book struct {
 	ID            string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID        string         `gorm:"type:uuid;index;references:User" json:"userId"`   // Relation avec la table User
-	GoogleBooksID string         `gorm:"size:512" json:"googleBooksId"`                   // Taille augmentée pour les ID complexes
-	Status        string         `gorm:"size:128;default:'pending'" json:"status"`        // Valeur par défaut
-	Rating        int            `gorm:"check:rating >= 0 AND rating <= 5" json:"rating"` // Validation de range
+	UserID        string         `gorm:"type:uuid;index;references:User" json:"userId"` // Relation avec la table User
+	GoogleBooksID string         `gorm:"size:512" json:"googleBooksId"`                 // Taille augmentée pour les ID complexes
+	Status        string         `gorm:"size:128;default:'pending'" json:"status"`      // Valeur par défaut
 	Comment       string         `gorm:"type:text" json:"comment"`
 	Title         string         `gorm:"size:255;not null" json:"title"`
 	ImageUrl      string         `gorm:"type:text" json:"imageUrl"`
@@ -17,7 +44,37 @@ type Book struct {
 	PageCount     int            `gorm:"default:0" json:"pageCount"`    // Valeur par défaut
 	Genres        pq.StringArray `gorm:"type:text[]" json:"genres"`     // Utilisation de pq.StringArray
 	PublishedDate string         `gorm:"size:128" json:"publishedDate"`
+	ISBN10        string         `gorm:"size:10" json:"isbn10"`
+	ISBN13        string         `gorm:"size:13" json:"isbn13"` // Unique par utilisateur, voir database.ConnectDB
+
+	// Note en demi-étoiles de 0.5 à 5, nil = non noté
+	Rating *float64 `gorm:"type:decimal(2,1);check:rating >= 0.5 AND rating <= 5" json:"rating"`
+
+	// Édition
+	Format          string `gorm:"size:16;default:'paperback';not null" json:"format"`
+	Publisher       string `gorm:"size:255" json:"publisher"`
+	Language        string `gorm:"size:16" json:"language"`                                      // Code ISO 639 (ex: "fr")
+	DurationMinutes int    `gorm:"default:0;check:duration_minutes >= 0" json:"durationMinutes"` // Durée d'un livre audio
+	Narrator        string `gorm:"size:255" json:"narrator"`
+
+	// Suivi de la lecture
+	Progress     int        `gorm:"default:0;check:progress >= 0" json:"progress"`        // Dans l'unité ProgressUnit
+	ProgressUnit string     `gorm:"size:16;default:'pages';not null" json:"progressUnit"` // pages, percent ou minutes
+	StartDate    *time.Time `json:"startDate"`                                            // Début de lecture
+	EndDate      *time.Time `json:"endDate"`                                              // Fin de lecture
+
+	// Appartenance à une série
+	SeriesID     *string `gorm:"type:uuid;index" json:"seriesId"`
+	VolumeNumber int     `gorm:"default:0;check:volume_number >= 0" json:"volumeNumber"` // 0 = hors série ou inconnu
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `gorm:"default:1;not null" json:"version"` // Incrémentée à chaque modification, voir PatchBook
+
+	// Corbeille : un livre supprimé reste restaurable jusqu'à sa purge
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
 
 	// Relation supplémentaire si nécessaire
-	User User `gorm:"foreignKey:UserID" json:"-"`
+	User   User    `gorm:"foreignKey:UserID" json:"-"`
+	Series *Series `gorm:"foreignKey:SeriesID;constraint:OnDelete:SET NULL;" json:"-"`
 }
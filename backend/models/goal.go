@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Mesures suivies par un objectif de lecture
+const (
+	GoalBooks   = "books"   // Livres terminés
+	GoalPages   = "pages"   // Pages lues pendant les sessions
+	GoalMinutes = "minutes" // Minutes de lecture des sessions
+)
+
+// Périodes d'un objectif, qui recommence à chaque nouvelle période
+const (
+	GoalWeek  = "week" // Du lundi au dimanche
+	GoalMonth = "month"
+	GoalYear  = "year"
+)
+
+// ReadingGoal est un objectif de lecture renouvelé à chaque période (ex: 24 livres par an)
+type ReadingGoal struct {
+	ID                  string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID              string     `gorm:"type:uuid;not null;uniqueIndex:idx_reading_goals_user_metric_period" json:"userId"`
+	Metric              string     `gorm:"size:16;not null;uniqueIndex:idx_reading_goals_user_metric_period" json:"metric"` // Un objectif par mesure et période
+	Period              string     `gorm:"size:16;not null;uniqueIndex:idx_reading_goals_user_metric_period" json:"period"`
+	Target              int        `gorm:"not null;check:target > 0" json:"target"`
+	CompletedPeriods    int        `gorm:"default:0;not null" json:"completedPeriods"` // Périodes où l'objectif a été atteint
+	LastCompletedPeriod *time.Time `gorm:"type:date" json:"lastCompletedPeriod"`       // Début de la dernière période atteinte
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Actions enregistrées dans l'historique d'un livre
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"  // Mise à la corbeille
+	HistoryRestore = "restore" // Sortie de la corbeille
+	HistoryMerge   = "merge"   // Fusion d'un doublon dans ce livre, ou de ce livre dans un autre
+	HistorySession = "session" // Avancement par une session de lecture
+)
+
+// FieldChange est l'ancienne et la nouvelle valeur d'un champ
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// BookChanges associe le nom JSON d'un champ du livre à sa modification
+type BookChanges map[string]FieldChange
+
+// Value enregistre les modifications en JSON
+func (c BookChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	value, err := json.Marshal(c)
+	return string(value), err
+}
+
+// Scan relit les modifications enregistrées en JSON
+func (c *BookChanges) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	case nil:
+		*c = BookChanges{}
+		return nil
+	}
+	return errors.New("unsupported type for BookChanges")
+}
+
+// BookHistory est une entrée de l'historique des modifications d'un livre
+type BookHistory struct {
+	ID        string      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	BookID    string      `gorm:"type:uuid;not null;index" json:"bookId"` // Sans clé étrangère : l'historique survit à la purge du livre
+	UserID    string      `gorm:"type:uuid;not null;index" json:"userId"` // Auteur de la modification
+	Action    string      `gorm:"size:16;not null" json:"action"`
+	Changes   BookChanges `gorm:"type:jsonb;not null;default:'{}'" json:"changes"`
+	CreatedAt time.Time   `gorm:"index" json:"createdAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Etats d'un import en arrière-plan
+const (
+	ImportPending = "pending"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+type ImportJob struct {
+	ID           string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       string     `gorm:"type:uuid;index;not null" json:"userId"`
+	Source       string     `gorm:"size:50;not null" json:"source"` // Ex: "goodreads", "kindle"
+	Status       string     `gorm:"size:20;not null;default:'pending'" json:"status"`
+	Total        int        `gorm:"default:0;not null" json:"total"`        // Lignes à traiter
+	Processed    int        `gorm:"default:0;not null" json:"processed"`    // Lignes traitées
+	Imported     int        `gorm:"default:0;not null" json:"imported"`     // Livres créés, ou notes pour un import Kindle
+	CreatedBooks int        `gorm:"default:0;not null" json:"createdBooks"` // Livres créés pour rattacher des notes
+	Skipped      int        `gorm:"default:0;not null" json:"skipped"`      // Doublons ignorés
+	Failed       int        `gorm:"default:0;not null" json:"failed"`       // Lignes en erreur
+	Error        string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	FinishedAt   *time.Time `json:"finishedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
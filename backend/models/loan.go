@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Loan est le prêt d'un livre à une personne, inscrite ou non
+type Loan struct {
+	ID             string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID         string     `gorm:"type:uuid;not null;index" json:"userId"` // Prêteur, propriétaire du livre
+	BookID         string     `gorm:"type:uuid;not null;index" json:"bookId"` // Un seul prêt en cours par livre, voir database.ConnectDB
+	BorrowerName   string     `gorm:"size:255;not null" json:"borrowerName"`
+	BorrowerEmail  string     `gorm:"size:255" json:"borrowerEmail"`
+	BorrowerUserID *string    `gorm:"type:uuid;index" json:"-"` // Emprunteur inscrit, retrouvé par son email, jamais renvoyé au prêteur
+	AcceptedAt     *time.Time `json:"acceptedAt"`               // Nil tant que l'emprunteur inscrit n'a pas accepté le prêt
+	LentAt         time.Time  `gorm:"type:date;not null" json:"lentAt"`
+	DueAt          *time.Time `gorm:"type:date" json:"dueAt"`      // Date de retour prévue
+	ReturnedAt     *time.Time `gorm:"type:date" json:"returnedAt"` // Nil tant que le livre n'est pas rendu
+	Note           string     `gorm:"type:text" json:"note"`
+	SendReminders  bool       `gorm:"default:false;not null" json:"sendReminders"` // Rappels par email une fois en retard
+	RemindedAt     *time.Time `json:"remindedAt"`                                  // Dernier rappel envoyé
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+
+	Book     Book  `gorm:"foreignKey:BookID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	User     User  `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Borrower *User `gorm:"foreignKey:BorrowerUserID;constraint:OnDelete:SET NULL;" json:"-"`
+}
+
+// IsOverdue indique si le livre n'est pas rendu après le jour prévu
+func (l *Loan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && l.DueAt != nil && l.DueAt.Format("2006-01-02") < now.Format("2006-01-02")
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// MetadataCache stocke les réponses des fournisseurs de métadonnées
+type MetadataCache struct {
+	Key       string    `gorm:"size:512;primaryKey"` // Ex: "google:volume:<id>"
+	Payload   string    `gorm:"type:jsonb;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Types de notes possibles pour un livre
+const (
+	NoteQuote     = "quote"
+	NoteHighlight = "highlight"
+	NoteNote      = "note"
+)
+
+// BookNote est une citation, un passage surligné ou une note personnelle sur un livre
+type BookNote struct {
+	ID          string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      string    `gorm:"type:uuid;not null;index" json:"userId"`
+	BookID      string    `gorm:"type:uuid;not null;index" json:"bookId"`
+	Type        string    `gorm:"size:16;not null;default:'note'" json:"type"`
+	Body        string    `gorm:"type:text;not null" json:"body"`         // Markdown
+	Page        int       `gorm:"default:0;check:page >= 0" json:"page"`  // 0 = inconnue
+	Location    string    `gorm:"size:64" json:"location"`                // Emplacement liseuse (ex: "1234-1240")
+	IsPublic    bool      `gorm:"default:false;not null" json:"isPublic"` // Visible seulement si le profil est public
+	Fingerprint string    `gorm:"size:64" json:"-"`                       // Empreinte des notes importées, voir database.ConnectDB
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	Book Book `gorm:"foreignKey:BookID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Series regroupe les tomes d'une même saga (ex: "Harry Potter", "Les Rougon-Macquart")
+type Series struct {
+	ID           string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       string    `gorm:"type:uuid;not null;uniqueIndex:idx_series_user_name" json:"userId"`
+	Name         string    `gorm:"size:255;not null;uniqueIndex:idx_series_user_name" json:"name"`  // Unique par utilisateur
+	TotalVolumes int       `gorm:"default:0;not null;check:total_volumes >= 0" json:"totalVolumes"` // 0 = inconnu
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type ReadingSession struct {
+	ID              string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID          string     `gorm:"type:uuid;index;not null" json:"userId"`
+	BookID          string     `gorm:"type:uuid;index;not null" json:"bookId"`
+	Date            time.Time  `gorm:"type:date;index;not null" json:"date"`                 // Jour de la session
+	StartedAt       *time.Time `json:"startedAt"`                                            // Session chronométrée
+	EndedAt         *time.Time `json:"endedAt"`                                              // Nil tant que la session est en cours
+	Pages           int        `gorm:"default:0;not null;check:pages >= 0" json:"pages"`     // Pages lues pendant la session
+	Minutes         int        `gorm:"default:0;not null;check:minutes >= 0" json:"minutes"` // Durée de lecture
+	AppliedProgress *int       `json:"appliedProgress"`                                      // Avancement appliqué au livre, limité à sa fin ; nil pour les anciennes sessions
+	Note            string     `gorm:"type:text" json:"note"`
+	CreatedAt       time.Time  `json:"createdAt"`
+
+	Book Book `gorm:"foreignKey:BookID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// IsRunning indique si la session a été démarrée sans être arrêtée
+func (s *ReadingSession) IsRunning() bool {
+	return s.StartedAt != nil && s.EndedAt == nil
+}
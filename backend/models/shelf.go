@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Shelf est une étagère personnalisée (ex: "Été 2026", "Club de lecture")
+type Shelf struct {
+	ID          string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      string    `gorm:"type:uuid;not null;uniqueIndex:idx_shelves_user_name" json:"userId"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex:idx_shelves_user_name" json:"name"` // Unique par utilisateur
+	Description string    `gorm:"type:text" json:"description"`
+	IsPublic    bool      `gorm:"default:false;not null" json:"isPublic"` // Visible seulement si le profil est public
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	Books []ShelfBook `gorm:"foreignKey:ShelfID" json:"-"`
+	User  User        `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// ShelfBook relie un livre à une étagère avec sa position
+type ShelfBook struct {
+	ShelfID  string    `gorm:"type:uuid;primaryKey" json:"shelfId"`
+	BookID   string    `gorm:"type:uuid;primaryKey;index" json:"bookId"`
+	Position int       `gorm:"default:0;not null" json:"position"` // Ordre dans l'étagère
+	AddedAt  time.Time `gorm:"autoCreateTime" json:"addedAt"`
+
+	Shelf Shelf `gorm:"foreignKey:ShelfID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Book  Book  `gorm:"foreignKey:BookID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
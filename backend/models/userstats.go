@@ -1,14 +1,28 @@
 package models
 
+import "time"
+
 type UserStat struct {
-	UserID         string  `gorm:"type:uuid;primaryKey"`
-	TotalBooks     int     `gorm:"default:0;not null"` // Tous statuts
-	CompletedBooks int     `gorm:"default:0;not null"` // Status completed
-	ToReadBooks    int     `gorm:"default:0;not null"` // Status toread
-	ReadingBooks   int     `gorm:"default:0;not null"` // Status reading
-	FavoriteBooks  int     `gorm:"default:0;not null"`
-	TotalPages     int     `gorm:"default:0;not null"`
-	AverageRating  float64 `gorm:"type:decimal(3,2)"`
+	UserID           string  `gorm:"type:uuid;primaryKey"`
+	TotalBooks       int     `gorm:"default:0;not null"` // Tous statuts
+	CompletedBooks   int     `gorm:"default:0;not null"` // Status completed
+	ToReadBooks      int     `gorm:"default:0;not null"` // Status toread
+	ReadingBooks     int     `gorm:"default:0;not null"` // Status reading
+	AbandonedBooks   int     `gorm:"default:0;not null"` // Status abandoned
+	FavoriteBooks    int     `gorm:"default:0;not null"`
+	TotalPages       int     `gorm:"default:0;not null"` // Hors livres audio
+	ListeningMinutes int     `gorm:"default:0;not null"` // Durée des livres audio
+	CompletedSeries  int     `gorm:"default:0;not null"` // Séries dont tous les tomes sont lus
+	RatedBooks       int     `gorm:"default:0;not null"` // Livres notés, base de AverageRating
+	AverageRating    float64 `gorm:"type:decimal(3,2)"`  // Moyenne des livres notés uniquement
+	DistinctGenres   int     `gorm:"default:0;not null"` // Genres différents parmi les livres lus
+	DistinctAuthors  int     `gorm:"default:0;not null"` // Auteurs différents parmi les livres lus
+	CompletedGoals   int     `gorm:"default:0;not null"` // Périodes où un objectif de lecture a été atteint
+
+	// Jours consécutifs avec une session de lecture ou une progression
+	CurrentStreak   int        `gorm:"default:0;not null"` // Série finissant au dernier jour de lecture
+	LongestStreak   int        `gorm:"default:0;not null"`
+	LastReadingDate *time.Time `gorm:"type:date"`
 
 	User User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -30,11 +30,84 @@ func SetUpRoutes(app *fiber.App) {
 
 	app.Get("/api/books", middleware.Protected(), controllers.GetBooks)
 	app.Post("/api/addbook", middleware.Protected(), controllers.AddBook)
+	app.Post("/api/books/isbn/:isbn", middleware.Protected(), controllers.AddBookByISBN)
+	app.Post("/api/books/batch", middleware.Protected(), controllers.BatchBooks)
 	app.Delete("/api/books/:id", middleware.Protected(), controllers.DeleteBook)
 	app.Put("/api/books/:id", middleware.Protected(), controllers.UpdateBook)
+	app.Patch("/api/books/:id", middleware.Protected(), controllers.PatchBook)
+	app.Get("/api/books/:id/history", middleware.Protected(), controllers.GetBookHistory)
+	app.Post("/api/books/:id/merge", middleware.Protected(), controllers.MergeBooks)
+
+	// trash
+	app.Get("/api/trash", middleware.Protected(), controllers.GetTrash)
+	app.Post("/api/trash/:id/restore", middleware.Protected(), controllers.RestoreBook)
+	app.Delete("/api/trash/:id", middleware.Protected(), controllers.PurgeBook)
+	app.Delete("/api/trash", middleware.Protected(), controllers.EmptyTrash)
+
+	// reading sessions
+	app.Get("/api/books/:id/sessions", middleware.Protected(), controllers.GetBookSessions)
+	app.Post("/api/books/:id/sessions", middleware.Protected(), controllers.LogSession)
+	app.Post("/api/books/:id/sessions/start", middleware.Protected(), controllers.StartSession)
+	app.Post("/api/sessions/:id/stop", middleware.Protected(), controllers.StopSession)
+	app.Delete("/api/sessions/:id", middleware.Protected(), controllers.DeleteSession)
+	app.Get("/api/sessions/daily", middleware.Protected(), controllers.GetDailyReading)
+
+	// notes
+	app.Get("/api/books/:id/notes", middleware.Protected(), controllers.GetBookNotes)
+	app.Post("/api/books/:id/notes", middleware.Protected(), controllers.AddNote)
+	app.Get("/api/notes", middleware.Protected(), controllers.SearchNotes)
+	app.Put("/api/notes/:id", middleware.Protected(), controllers.UpdateNote)
+	app.Delete("/api/notes/:id", middleware.Protected(), controllers.DeleteNote)
+
+	// shelves
+	app.Get("/api/shelves", middleware.Protected(), controllers.GetShelves)
+	app.Post("/api/shelves", middleware.Protected(), controllers.CreateShelf)
+	app.Get("/api/shelves/:id", middleware.Protected(), controllers.GetShelf)
+	app.Put("/api/shelves/:id", middleware.Protected(), controllers.UpdateShelf)
+	app.Delete("/api/shelves/:id", middleware.Protected(), controllers.DeleteShelf)
+	app.Post("/api/shelves/:id/books", middleware.Protected(), controllers.AddBookToShelf)
+	app.Delete("/api/shelves/:id/books/:bookId", middleware.Protected(), controllers.RemoveBookFromShelf)
+	app.Put("/api/shelves/:id/order", middleware.Protected(), controllers.ReorderShelf)
+
+	// loans
+	app.Get("/api/loans", middleware.Protected(), controllers.GetLoans)
+	app.Get("/api/loans/borrowed", middleware.Protected(), controllers.GetBorrowedBooks)
+	app.Get("/api/loans/requests", middleware.Protected(), controllers.GetLoanRequests)
+	app.Post("/api/loans/:id/accept", middleware.Protected(), controllers.AcceptLoan)
+	app.Post("/api/loans/:id/decline", middleware.Protected(), controllers.DeclineLoan)
+	app.Post("/api/loans/:id/return", middleware.Protected(), controllers.ReturnLoan)
+	app.Get("/api/books/:id/loans", middleware.Protected(), controllers.GetBookLoans)
+	app.Post("/api/books/:id/loans", middleware.Protected(), controllers.LendBook)
+
+	// series
+	app.Get("/api/series", middleware.Protected(), controllers.GetSeries)
+	app.Post("/api/series", middleware.Protected(), controllers.CreateSeries)
+	app.Get("/api/series/:id", middleware.Protected(), controllers.GetSeriesDetail)
+	app.Put("/api/series/:id", middleware.Protected(), controllers.UpdateSeries)
+	app.Delete("/api/series/:id", middleware.Protected(), controllers.DeleteSeries)
+
+	// imports
+	app.Post("/api/import/goodreads", middleware.Protected(), controllers.ImportGoodreads)
+	app.Post("/api/import/kindle", middleware.Protected(), controllers.ImportKindle)
+	app.Get("/api/import/:id", middleware.Protected(), controllers.GetImportJob)
+
+	// book metadata
+	app.Get("/api/metadata/search", middleware.Protected(), controllers.SearchMetadata)
+	app.Get("/api/metadata/volume/:id", middleware.Protected(), controllers.GetMetadataVolume)
+
+	// export
+	app.Get("/api/export", middleware.Protected(), controllers.ExportLibrary)
 
 	// stats
 	app.Get("/api/stats", middleware.Protected(), controllers.GetStats)
+	app.Get("/api/stats/timeline", middleware.Protected(), controllers.GetStatsTimeline)
+	app.Get("/api/stats/breakdown", middleware.Protected(), controllers.GetStatsBreakdown)
+
+	// reading goals
+	app.Get("/api/goals", middleware.Protected(), controllers.GetGoals)
+	app.Post("/api/goals", middleware.Protected(), controllers.CreateGoal)
+	app.Put("/api/goals/:id", middleware.Protected(), controllers.UpdateGoal)
+	app.Delete("/api/goals/:id", middleware.Protected(), controllers.DeleteGoal)
 
 	// achievements
 	app.Get("/api/achievements", middleware.Protected(), controllers.GetAchievements)
@@ -33,6 +33,19 @@ func (s *AchievementService) CheckAchievements(userID string) error {
 			return fmt.Errorf("user stats not found: %w", err)
 		}
 
+		// Objectifs de lecture atteints depuis la dernière vérification
+		completedGoals, err := s.checkGoals(tx, userID)
+		if err != nil {
+			sugar.Errorw("Failed to check reading goals", "userID", userID, "error", err)
+			return err
+		}
+		if completedGoals != stat.CompletedGoals {
+			stat.CompletedGoals = completedGoals
+			if err := tx.Model(&stat).Update("completed_goals", completedGoals).Error; err != nil {
+				return fmt.Errorf("failed to update completed goals: %w", err)
+			}
+		}
+
 		// Récupération de tous les succès activables par les stats
 		var achievements []models.Achievement
 		if err := tx.Find(&achievements).Error; err != nil {
@@ -146,10 +159,26 @@ func getStatValue(stat *models.UserStat, field string) (int, bool) {
 		return stat.CompletedBooks, true
 	case "ReadingBooks":
 		return stat.ReadingBooks, true
+	case "AbandonedBooks":
+		return stat.AbandonedBooks, true
 	case "TotalPages":
 		return stat.TotalPages, true
+	case "ListeningMinutes":
+		return stat.ListeningMinutes, true
 	case "FavoriteBooks":
 		return stat.FavoriteBooks, true
+	case "CompletedSeries":
+		return stat.CompletedSeries, true
+	case "DistinctGenres":
+		return stat.DistinctGenres, true
+	case "DistinctAuthors":
+		return stat.DistinctAuthors, true
+	case "CompletedGoals":
+		return stat.CompletedGoals, true
+	case "CurrentStreak":
+		return stat.CurrentStreak, true
+	case "LongestStreak":
+		return stat.LongestStreak, true
 	default:
 		return 0, false // Ignore les succès non liés aux stats
 	}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"booksrendezvous-backend/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GoalPeriod returns the start, included, and the end, excluded, of the goal period containing now.
+// Weeks start on Monday.
+func GoalPeriod(period string, now time.Time) (time.Time, time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case models.GoalWeek:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case models.GoalMonth:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(1, 0, 0)
+	}
+}
+
+// GoalProgress measures a goal between start and end: books finished, or pages or minutes
+// of the finished reading sessions, books in the trash excluded
+func GoalProgress(db *gorm.DB, goal models.ReadingGoal, start time.Time, end time.Time) (int, error) {
+	var progress int64
+	if goal.Metric == models.GoalBooks {
+		err := db.Model(&models.Book{}).
+			Where("user_id = ? AND status = ? AND end_date >= ? AND end_date < ?", goal.UserID, models.StatusFinished, start, end).
+			Count(&progress).Error
+		return int(progress), err
+	}
+
+	column := "pages"
+	if goal.Metric == models.GoalMinutes {
+		column = "minutes"
+	}
+	err := db.Model(&models.ReadingSession{}).
+		Select("COALESCE(SUM("+column+"), 0)").
+		Where("user_id = ? AND date >= ? AND date < ?", goal.UserID, start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Where("NOT (started_at IS NOT NULL AND ended_at IS NULL)").
+		Where("book_id IN (SELECT id FROM books WHERE deleted_at IS NULL)").
+		Scan(&progress).Error
+	return int(progress), err
+}
+
+// Enregistre les objectifs atteints sur leur période en cours, et renvoie le total des périodes atteintes
+func (s *AchievementService) checkGoals(tx *gorm.DB, userID string) (int, error) {
+	var goals []models.ReadingGoal
+	if err := tx.Where("user_id = ?", userID).Find(&goals).Error; err != nil {
+		return 0, fmt.Errorf("failed to get reading goals: %w", err)
+	}
+
+	now := time.Now()
+	completed := 0
+	for _, goal := range goals {
+		start, end := GoalPeriod(goal.Period, now)
+		alreadyCompleted := goal.LastCompletedPeriod != nil &&
+			goal.LastCompletedPeriod.Format("2006-01-02") == start.Format("2006-01-02")
+
+		if !alreadyCompleted {
+			progress, err := GoalProgress(tx, goal, start, end)
+			if err != nil {
+				return 0, fmt.Errorf("failed to measure reading goal: %w", err)
+			}
+			if progress >= goal.Target {
+				goal.CompletedPeriods++
+				goal.LastCompletedPeriod = &start
+				if err := tx.Model(&goal).Updates(map[string]interface{}{
+					"completed_periods":     goal.CompletedPeriods,
+					"last_completed_period": start,
+				}).Error; err != nil {
+					return 0, fmt.Errorf("failed to save reading goal: %w", err)
+				}
+				sugar.Infow("Reading goal completed", "userID", userID, "metric", goal.Metric, "period", goal.Period)
+			}
+		}
+		completed += goal.CompletedPeriods
+	}
+	return completed, nil
+}
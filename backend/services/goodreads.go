@@ -0,0 +1,238 @@
+package services
+
+import (
+	"booksrendezvous-backend/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoodreadsEntry is one row of a Goodreads library export
+type GoodreadsEntry struct {
+	Title         string
+	Authors       []string
+	ISBN          string
+	ISBN13        string
+	Rating        int
+	Review        string
+	Shelf         string
+	PageCount     int
+	Publisher     string
+	Binding       string // Ex: "Paperback", "Kindle Edition", "Audible Audio"
+	PublishedYear string
+	DateRead      *time.Time
+	DateAdded     *time.Time
+}
+
+// Status maps the Goodreads exclusive shelf to a BooksRendezVous status
+func (e GoodreadsEntry) Status() string {
+	switch e.Shelf {
+	case "read":
+		return models.StatusFinished
+	case "currently-reading":
+		return models.StatusReading
+	case "did-not-finish", "dnf", "abandoned":
+		return models.StatusAbandoned
+	default:
+		return models.StatusToRead
+	}
+}
+
+// Format maps the Goodreads binding to a BooksRendezVous format
+func (e GoodreadsEntry) Format() string {
+	binding := strings.ToLower(e.Binding)
+	switch {
+	case strings.Contains(binding, "audio"):
+		return models.FormatAudiobook
+	case strings.Contains(binding, "kindle"), strings.Contains(binding, "ebook"),
+		strings.Contains(binding, "nook"), strings.Contains(binding, "digital"):
+		return models.FormatEbook
+	case strings.Contains(binding, "hardcover"):
+		return models.FormatHardcover
+	default:
+		return models.FormatPaperback
+	}
+}
+
+// GoodreadsBinding maps a BooksRendezVous format to a Goodreads binding
+func GoodreadsBinding(format string) string {
+	switch format {
+	case models.FormatHardcover:
+		return "Hardcover"
+	case models.FormatEbook:
+		return "ebook"
+	case models.FormatAudiobook:
+		return "Audiobook"
+	default:
+		return "Paperback"
+	}
+}
+
+// GoodreadsShelf maps a BooksRendezVous status to the Goodreads exclusive shelf
+func GoodreadsShelf(status string) string {
+	switch status {
+	case models.StatusFinished:
+		return "read"
+	case models.StatusReading:
+		return "currently-reading"
+	case models.StatusAbandoned:
+		return "did-not-finish"
+	default:
+		return "to-read"
+	}
+}
+
+// GoodreadsHeader lists the columns written by GoodreadsRecord, readable by Goodreads and ParseGoodreadsCSV
+var GoodreadsHeader = []string{
+	"Title", "Author", "Additional Authors", "ISBN", "ISBN13", "My Rating",
+	"Publisher", "Binding", "Number of Pages", "Year Published", "Date Read", "Date Added",
+	"Bookshelves", "Exclusive Shelf", "My Review",
+}
+
+// GoodreadsRecord converts a book into a Goodreads-compatible CSV row
+func GoodreadsRecord(book models.Book) []string {
+	author, additional := "", ""
+	if len(book.Authors) > 0 {
+		author = book.Authors[0]
+		additional = strings.Join(book.Authors[1:], ", ")
+	}
+
+	year := book.PublishedDate
+	if len(year) > 4 {
+		year = year[:4]
+	}
+
+	// Goodreads n'accepte que des étoiles entières, 0 = non noté
+	rating := 0
+	if book.Rating != nil {
+		rating = int(math.Round(*book.Rating))
+	}
+
+	shelf := GoodreadsShelf(book.Status)
+	return []string{
+		book.Title,
+		author,
+		additional,
+		book.ISBN10,
+		book.ISBN13,
+		strconv.Itoa(rating),
+		book.Publisher,
+		GoodreadsBinding(book.Format),
+		strconv.Itoa(book.PageCount),
+		year,
+		formatGoodreadsDate(book.EndDate),
+		formatGoodreadsDate(&book.CreatedAt),
+		shelf,
+		shelf,
+		strings.ReplaceAll(book.Comment, "\n", "<br/>"),
+	}
+}
+
+// Colonnes obligatoires de l'export Goodreads
+var goodreadsRequiredColumns = []string{"Title", "Author", "Exclusive Shelf"}
+
+// ParseGoodreadsCSV reads a Goodreads export and returns its rows
+func ParseGoodreadsCSV(r io.Reader) ([]GoodreadsEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range goodreadsRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing goodreads column %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []GoodreadsEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv row: %w", err)
+		}
+
+		entry := GoodreadsEntry{
+			Title:         field(record, "Title"),
+			ISBN:          cleanGoodreadsISBN(field(record, "ISBN")),
+			ISBN13:        cleanGoodreadsISBN(field(record, "ISBN13")),
+			Review:        strings.ReplaceAll(field(record, "My Review"), "<br/>", "\n"),
+			Shelf:         field(record, "Exclusive Shelf"),
+			Publisher:     field(record, "Publisher"),
+			Binding:       field(record, "Binding"),
+			PublishedYear: field(record, "Original Publication Year"),
+			DateRead:      parseGoodreadsDate(field(record, "Date Read")),
+			DateAdded:     parseGoodreadsDate(field(record, "Date Added")),
+		}
+		if entry.Title == "" {
+			continue
+		}
+		if entry.PublishedYear == "" {
+			entry.PublishedYear = field(record, "Year Published")
+		}
+
+		if author := field(record, "Author"); author != "" {
+			entry.Authors = append(entry.Authors, author)
+		}
+		for _, author := range strings.Split(field(record, "Additional Authors"), ",") {
+			if author = strings.TrimSpace(author); author != "" {
+				entry.Authors = append(entry.Authors, author)
+			}
+		}
+
+		entry.Rating, _ = strconv.Atoi(field(record, "My Rating"))
+		entry.PageCount, _ = strconv.Atoi(field(record, "Number of Pages"))
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// cleanGoodreadsISBN removes the ="..." wrapping Goodreads puts around ISBNs
+func cleanGoodreadsISBN(value string) string {
+	value = strings.TrimPrefix(value, "=")
+	return strings.Trim(value, `"`)
+}
+
+func formatGoodreadsDate(date *time.Time) string {
+	if date == nil || date.IsZero() {
+		return ""
+	}
+	return date.Format("2006/01/02")
+}
+
+func parseGoodreadsDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{"2006/01/02", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
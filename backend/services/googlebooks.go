@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limiteur partagé par toutes les requêtes vers Google Books
+var googleBooksLimiter = NewRateLimiter(config.MetadataRateLimit)
+
+// GoogleBooksClient fetches volumes from the Google Books API through the metadata cache.
+// BaseURL and HTTP can be replaced to point the client at a fake server, a nil Cache disables caching.
+type GoogleBooksClient struct {
+	BaseURL string
+	APIKey  string
+	HTTP    *http.Client
+	Cache   *MetadataCache
+	Limiter *RateLimiter
+}
+
+func NewGoogleBooksClient(db *gorm.DB) *GoogleBooksClient {
+	return &GoogleBooksClient{
+		BaseURL: strings.TrimRight(config.GoogleBooksURL, "/"),
+		APIKey:  config.GoogleBooksAPIKey,
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+		Cache:   NewMetadataCache(db),
+		Limiter: googleBooksLimiter,
+	}
+}
+
+func (c *GoogleBooksClient) Name() string {
+	return "google"
+}
+
+type googleVolume struct {
+	ID         string `json:"id"`
+	VolumeInfo struct {
+		Title               string   `json:"title"`
+		Authors             []string `json:"authors"`
+		Publisher           string   `json:"publisher"`
+		PublishedDate       string   `json:"publishedDate"`
+		Description         string   `json:"description"`
+		PageCount           int      `json:"pageCount"`
+		Categories          []string `json:"categories"`
+		Language            string   `json:"language"`
+		IndustryIdentifiers []struct {
+			Type       string `json:"type"`
+			Identifier string `json:"identifier"`
+		} `json:"industryIdentifiers"`
+		ImageLinks map[string]string `json:"imageLinks"`
+		SeriesInfo *struct {
+			BookDisplayNumber string `json:"bookDisplayNumber"`
+		} `json:"seriesInfo"`
+	} `json:"volumeInfo"`
+}
+
+type googleVolumes struct {
+	TotalItems int            `json:"totalItems"`
+	Items      []googleVolume `json:"items"`
+}
+
+// Volume returns the metadata of a Google Books volume by ID
+func (c *GoogleBooksClient) Volume(id string) (*BookMetadata, error) {
+	key := "google:volume:" + id
+
+	var cached *BookMetadata
+	if c.Cache != nil && c.Cache.Get(key, &cached) {
+		if cached == nil {
+			return nil, ErrMetadataNotFound
+		}
+		return cached, nil
+	}
+
+	var volume googleVolume
+	err := c.get("/volumes/"+url.PathEscape(id), nil, &volume)
+	if errors.Is(err, ErrMetadataNotFound) {
+		c.cache(key, nil)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := volume.toMetadata()
+	c.cache(key, metadata)
+	return metadata, nil
+}
+
+// Search runs a free-text Google Books query
+func (c *GoogleBooksClient) Search(query string, limit int) ([]BookMetadata, error) {
+	if limit <= 0 || limit > 40 {
+		limit = 10
+	}
+	key := fmt.Sprintf("google:search:%d:%s", limit, strings.ToLower(strings.TrimSpace(query)))
+
+	var cached []BookMetadata
+	if c.Cache != nil && c.Cache.Get(key, &cached) {
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("maxResults", strconv.Itoa(limit))
+
+	var volumes googleVolumes
+	if err := c.get("/volumes", params, &volumes); err != nil && !errors.Is(err, ErrMetadataNotFound) {
+		return nil, err
+	}
+
+	results := make([]BookMetadata, 0, len(volumes.Items))
+	for _, volume := range volumes.Items {
+		results = append(results, *volume.toMetadata())
+	}
+	c.cache(key, results)
+	return results, nil
+}
+
+// ISBN returns the first Google Books volume matching an ISBN
+func (c *GoogleBooksClient) ISBN(isbn string) (*BookMetadata, error) {
+	results, err := c.Search("isbn:"+isbn, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrMetadataNotFound
+	}
+	return &results[0], nil
+}
+
+// get builds the request URL, adding the API key when configured
+func (c *GoogleBooksClient) get(path string, params url.Values, dest interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.APIKey != "" {
+		params.Set("key", c.APIKey)
+	}
+	endpoint := c.BaseURL + path
+	if encoded := params.Encode(); encoded != "" {
+		endpoint += "?" + encoded
+	}
+
+	return fetchJSON(c.HTTP, c.Limiter, endpoint, dest)
+}
+
+func (c *GoogleBooksClient) cache(key string, value interface{}) {
+	if c.Cache == nil {
+		return
+	}
+	if err := c.Cache.Set(key, value); err != nil {
+		sugar.Warnw("Failed to cache metadata", "key", key, "error", err)
+	}
+}
+
+func (v googleVolume) toMetadata() *BookMetadata {
+	info := v.VolumeInfo
+	metadata := &BookMetadata{
+		GoogleBooksID: v.ID,
+		Title:         info.Title,
+		Authors:       info.Authors,
+		Description:   info.Description,
+		PageCount:     info.PageCount,
+		Genres:        info.Categories,
+		PublishedDate: info.PublishedDate,
+		Publisher:     info.Publisher,
+		Language:      info.Language,
+	}
+
+	for _, identifier := range info.IndustryIdentifiers {
+		switch identifier.Type {
+		case "ISBN_10":
+			metadata.ISBN10 = identifier.Identifier
+		case "ISBN_13":
+			metadata.ISBN13 = identifier.Identifier
+		}
+	}
+
+	// Google Books ne donne que le numéro du tome, pas le nom de la série
+	if info.SeriesInfo != nil {
+		if volume, err := strconv.Atoi(strings.TrimSpace(info.SeriesInfo.BookDisplayNumber)); err == nil && volume > 0 {
+			metadata.VolumeNumber = volume
+		}
+	}
+
+	// Meilleure image disponible, servie en https
+	for _, size := range []string{"large", "medium", "small", "thumbnail", "smallThumbnail"} {
+		if link := info.ImageLinks[size]; link != "" {
+			metadata.ImageURL = strings.Replace(link, "http://", "https://", 1)
+			break
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"booksrendezvous-backend/models"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Séparateur des entrées de "My Clippings.txt"
+const kindleSeparator = "=========="
+
+var (
+	kindleTitlePattern    = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	kindlePagePattern     = regexp.MustCompile(`(?i)\b(?:page|p\.)\s+(\d+)`)
+	kindleLocationPattern = regexp.MustCompile(`(?i)\b(?:location|loc\.|emplacement|position)\s+(\d+(?:-\d+)?)`)
+	kindleAddedPattern    = regexp.MustCompile(`(?i)added on (.+)$`)
+)
+
+// Formats de date des liseuses en anglais
+var kindleDateLayouts = []string{
+	"Monday, January 2, 2006 3:04:05 PM",
+	"Monday, 2 January 2006 15:04:05",
+	"Monday, January 2, 2006, 3:04:05 PM",
+}
+
+// KindleClipping is one highlight or note of a Kindle "My Clippings.txt" file
+type KindleClipping struct {
+	Title    string
+	Author   string
+	Type     string // models.NoteHighlight ou models.NoteNote
+	Page     int
+	Location string
+	Body     string
+	AddedAt  *time.Time
+}
+
+// Fingerprint identifies a clipping so that uploading the same file twice does not duplicate notes
+func (k KindleClipping) Fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.ToLower(k.Title), strings.ToLower(k.Author), k.Type, k.Location, strconv.Itoa(k.Page), k.Body,
+	}, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseKindleClippings reads a "My Clippings.txt" file. Bookmarks and empty entries are skipped.
+func ParseKindleClippings(r io.Reader) ([]KindleClipping, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var clippings []KindleClipping
+	var lines []string
+	entries := 0
+	flush := func() {
+		if clipping, ok := parseKindleEntry(lines); ok {
+			clippings = append(clippings, clipping)
+		}
+		lines = lines[:0]
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == kindleSeparator {
+			entries++
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	if entries == 0 && len(clippings) == 0 {
+		return nil, errors.New("no clippings found, is this a My Clippings.txt file?")
+	}
+	return clippings, nil
+}
+
+// parseKindleEntry parses the title line, the metadata line and the text of one entry
+func parseKindleEntry(lines []string) (KindleClipping, bool) {
+	// Lignes vides en tête d'entrée
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) < 2 {
+		return KindleClipping{}, false
+	}
+
+	var clipping KindleClipping
+	titleLine := strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff"))
+	if match := kindleTitlePattern.FindStringSubmatch(titleLine); match != nil && match[1] != "" {
+		clipping.Title = strings.TrimSpace(match[1])
+		clipping.Author = strings.TrimSpace(match[2])
+	} else {
+		clipping.Title = titleLine
+	}
+
+	meta := strings.ToLower(lines[1])
+	switch {
+	case strings.Contains(meta, "bookmark"), strings.Contains(meta, "signet"):
+		return KindleClipping{}, false
+	case strings.Contains(meta, "note"):
+		clipping.Type = models.NoteNote
+	default:
+		clipping.Type = models.NoteHighlight
+	}
+
+	if match := kindlePagePattern.FindStringSubmatch(lines[1]); match != nil {
+		clipping.Page, _ = strconv.Atoi(match[1])
+	}
+	if match := kindleLocationPattern.FindStringSubmatch(lines[1]); match != nil {
+		clipping.Location = match[1]
+	}
+	if match := kindleAddedPattern.FindStringSubmatch(lines[1]); match != nil {
+		for _, layout := range kindleDateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(match[1])); err == nil {
+				clipping.AddedAt = &t
+				break
+			}
+		}
+	}
+
+	clipping.Body = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	if clipping.Title == "" || clipping.Body == "" {
+		return KindleClipping{}, false
+	}
+	return clipping, true
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"booksrendezvous-backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMetadataNotFound est renvoyée quand aucun volume ne correspond
+var ErrMetadataNotFound = errors.New("metadata not found")
+
+// Nombre maximal de tentatives lorsqu'un fournisseur répond 429 ou 5xx
+const metadataMaxAttempts = 3
+
+// BookMetadata is the normalized description of a volume returned by metadata providers
+type BookMetadata struct {
+	GoogleBooksID string   `json:"googleBooksId"`
+	Title         string   `json:"title"`
+	Authors       []string `json:"authors"`
+	Description   string   `json:"description"`
+	ImageURL      string   `json:"imageUrl"`
+	PageCount     int      `json:"pageCount"`
+	Genres        []string `json:"genres"`
+	PublishedDate string   `json:"publishedDate"`
+	Publisher     string   `json:"publisher"`
+	Language      string   `json:"language"`
+	ISBN10        string   `json:"isbn10"`
+	ISBN13        string   `json:"isbn13"`
+	SeriesName    string   `json:"seriesName"`
+	VolumeNumber  int      `json:"volumeNumber"`
+}
+
+// MetadataCache persists provider responses in Postgres with a TTL
+type MetadataCache struct {
+	DB  *gorm.DB
+	TTL time.Duration
+}
+
+func NewMetadataCache(db *gorm.DB) *MetadataCache {
+	return &MetadataCache{DB: db, TTL: time.Duration(config.MetadataCacheHours) * time.Hour}
+}
+
+// Get decodes a non-expired cache entry into dest and reports whether it was found
+func (c *MetadataCache) Get(key string, dest interface{}) bool {
+	var entry models.MetadataCache
+	if err := c.DB.Where("key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error; err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(entry.Payload), dest); err != nil {
+		sugar.Warnw("Invalid metadata cache entry", "key", key, "error", err)
+		return false
+	}
+	return true
+}
+
+// Set stores a value in the cache, replacing any previous entry
+func (c *MetadataCache) Set(key string, value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	entry := models.MetadataCache{
+		Key:       key,
+		Payload:   string(payload),
+		ExpiresAt: time.Now().Add(c.TTL),
+	}
+	return c.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"payload", "expires_at", "updated_at"}),
+	}).Create(&entry).Error
+}
+
+// RateLimiter spaces out calls so that at most N happen per second
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func NewRateLimiter(perSecond int) *RateLimiter {
+	if perSecond <= 0 {
+		perSecond = 1
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the caller is allowed to issue its request
+func (l *RateLimiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+}
+
+// fetchJSON calls a provider endpoint with rate limiting and retries on throttling or server errors
+func fetchJSON(client *http.Client, limiter *RateLimiter, endpoint string, dest interface{}) error {
+	var lastErr error
+	for attempt := 1; attempt <= metadataMaxAttempts; attempt++ {
+		limiter.Wait()
+
+		resp, err := client.Get(endpoint)
+		if err != nil {
+			lastErr = fmt.Errorf("metadata request failed: %w", err)
+		} else {
+			lastErr = decodeJSONResponse(resp, dest)
+			if lastErr == nil || !isRetryable(resp.StatusCode) {
+				return lastErr
+			}
+		}
+
+		sugar.Warnw("Metadata request failed", "attempt", attempt, "error", lastErr)
+		time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+	}
+	return lastErr
+}
+
+func decodeJSONResponse(resp *http.Response, dest interface{}) error {
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrMetadataNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("metadata provider returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode metadata response: %w", err)
+	}
+	return nil
+}
+
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Nombre de sujets Open Library conservés comme genres
+const openLibraryMaxSubjects = 5
+
+// Limiteur partagé par toutes les requêtes vers Open Library
+var openLibraryLimiter = NewRateLimiter(config.MetadataRateLimit)
+
+// Numéro de tome en fin de libellé de série, annoncé par un marqueur : "Discworld ; 3", "Harry Potter #1",
+// "Les Misérables, t. 4", "Dune (Book 2)". Sans marqueur, "Catch-22" reste un titre.
+var seriesVolumePattern = regexp.MustCompile(`(?i)^(.*?)[\s,(]*(?:;|#|\b(?:vol(?:ume)?\.?|tome|t\.|book|no\.))\s*#?(\d+)\)?\s*$`)
+
+// OpenLibraryClient fetches editions from the Open Library API through the metadata cache.
+// BaseURL, CoversURL and HTTP can be replaced to point the client at a fake server, a nil Cache disables caching.
+type OpenLibraryClient struct {
+	BaseURL   string
+	CoversURL string
+	HTTP      *http.Client
+	Cache     *MetadataCache
+	Limiter   *RateLimiter
+}
+
+func NewOpenLibraryClient(db *gorm.DB) *OpenLibraryClient {
+	return &OpenLibraryClient{
+		BaseURL:   strings.TrimRight(config.OpenLibraryURL, "/"),
+		CoversURL: strings.TrimRight(config.OpenLibraryCovers, "/"),
+		HTTP:      &http.Client{Timeout: 10 * time.Second},
+		Cache:     NewMetadataCache(db),
+		Limiter:   openLibraryLimiter,
+	}
+}
+
+func (c *OpenLibraryClient) Name() string {
+	return "openlibrary"
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+type openLibraryEdition struct {
+	Title         string            `json:"title"`
+	Authors       []openLibraryName `json:"authors"`
+	Publishers    []openLibraryName `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	NumberOfPages int               `json:"number_of_pages"`
+	Subjects      []openLibraryName `json:"subjects"`
+	Cover         map[string]string `json:"cover"`
+	Identifiers   struct {
+		ISBN10 []string `json:"isbn_10"`
+		ISBN13 []string `json:"isbn_13"`
+	} `json:"identifiers"`
+}
+
+// openLibraryEditionRecord is the raw edition record, the only one exposing the series
+type openLibraryEditionRecord struct {
+	Series []string `json:"series"`
+}
+
+type openLibrarySearch struct {
+	Docs []struct {
+		Title            string   `json:"title"`
+		AuthorName       []string `json:"author_name"`
+		NumberOfPages    int      `json:"number_of_pages_median"`
+		CoverID          int      `json:"cover_i"`
+		Subject          []string `json:"subject"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		ISBN             []string `json:"isbn"`
+		Publisher        []string `json:"publisher"`
+		Language         []string `json:"language"`
+	} `json:"docs"`
+}
+
+// ISBN returns the Open Library edition matching an ISBN
+func (c *OpenLibraryClient) ISBN(isbn string) (*BookMetadata, error) {
+	key := "openlibrary:isbn:" + isbn
+
+	var cached *BookMetadata
+	if c.Cache != nil && c.Cache.Get(key, &cached) {
+		if cached == nil {
+			return nil, ErrMetadataNotFound
+		}
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("bibkeys", "ISBN:"+isbn)
+	params.Set("format", "json")
+	params.Set("jscmd", "data")
+
+	var editions map[string]openLibraryEdition
+	err := fetchJSON(c.HTTP, c.Limiter, c.BaseURL+"/api/books?"+params.Encode(), &editions)
+	if err != nil && !errors.Is(err, ErrMetadataNotFound) {
+		return nil, err
+	}
+
+	edition, ok := editions["ISBN:"+isbn]
+	if !ok {
+		c.cache(key, nil)
+		return nil, ErrMetadataNotFound
+	}
+
+	metadata := edition.toMetadata()
+	if len(isbn) == 13 && metadata.ISBN13 == "" {
+		metadata.ISBN13 = isbn
+	} else if len(isbn) == 10 && metadata.ISBN10 == "" {
+		metadata.ISBN10 = isbn
+	}
+
+	// La série est facultative, un échec n'empêche pas de renvoyer l'édition
+	var record openLibraryEditionRecord
+	if err := fetchJSON(c.HTTP, c.Limiter, c.BaseURL+"/isbn/"+url.PathEscape(isbn)+".json", &record); err != nil {
+		sugar.Debugw("Failed to fetch Open Library edition record", "isbn", isbn, "error", err)
+	} else if len(record.Series) > 0 {
+		metadata.SeriesName, metadata.VolumeNumber = ParseSeriesLabel(record.Series[0])
+	}
+
+	c.cache(key, metadata)
+	return metadata, nil
+}
+
+// Search runs a free-text Open Library query
+func (c *OpenLibraryClient) Search(query string, limit int) ([]BookMetadata, error) {
+	if limit <= 0 || limit > 40 {
+		limit = 10
+	}
+	key := fmt.Sprintf("openlibrary:search:%d:%s", limit, strings.ToLower(strings.TrimSpace(query)))
+
+	var cached []BookMetadata
+	if c.Cache != nil && c.Cache.Get(key, &cached) {
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", strconv.Itoa(limit))
+
+	var search openLibrarySearch
+	err := fetchJSON(c.HTTP, c.Limiter, c.BaseURL+"/search.json?"+params.Encode(), &search)
+	if err != nil && !errors.Is(err, ErrMetadataNotFound) {
+		return nil, err
+	}
+
+	results := make([]BookMetadata, 0, len(search.Docs))
+	for _, doc := range search.Docs {
+		metadata := BookMetadata{
+			Title:     doc.Title,
+			Authors:   doc.AuthorName,
+			PageCount: doc.NumberOfPages,
+			Genres:    firstN(doc.Subject, openLibraryMaxSubjects),
+		}
+		if doc.FirstPublishYear > 0 {
+			metadata.PublishedDate = strconv.Itoa(doc.FirstPublishYear)
+		}
+		if doc.CoverID > 0 {
+			metadata.ImageURL = fmt.Sprintf("%s/b/id/%d-L.jpg", c.CoversURL, doc.CoverID)
+		}
+		if len(doc.Publisher) > 0 {
+			metadata.Publisher = doc.Publisher[0]
+		}
+		if len(doc.Language) > 0 {
+			metadata.Language = doc.Language[0]
+		}
+		for _, isbn := range doc.ISBN {
+			if len(isbn) == 13 && metadata.ISBN13 == "" {
+				metadata.ISBN13 = isbn
+			} else if len(isbn) == 10 && metadata.ISBN10 == "" {
+				metadata.ISBN10 = isbn
+			}
+		}
+		results = append(results, metadata)
+	}
+
+	c.cache(key, results)
+	return results, nil
+}
+
+func (c *OpenLibraryClient) cache(key string, value interface{}) {
+	if c.Cache == nil {
+		return
+	}
+	if err := c.Cache.Set(key, value); err != nil {
+		sugar.Warnw("Failed to cache metadata", "key", key, "error", err)
+	}
+}
+
+func (e openLibraryEdition) toMetadata() *BookMetadata {
+	metadata := &BookMetadata{
+		Title:         e.Title,
+		PageCount:     e.NumberOfPages,
+		PublishedDate: e.PublishDate,
+	}
+
+	for _, author := range e.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	for _, subject := range e.Subjects {
+		if len(metadata.Genres) == openLibraryMaxSubjects {
+			break
+		}
+		metadata.Genres = append(metadata.Genres, subject.Name)
+	}
+	if len(e.Publishers) > 0 {
+		metadata.Publisher = e.Publishers[0].Name
+	}
+	if len(e.Identifiers.ISBN10) > 0 {
+		metadata.ISBN10 = e.Identifiers.ISBN10[0]
+	}
+	if len(e.Identifiers.ISBN13) > 0 {
+		metadata.ISBN13 = e.Identifiers.ISBN13[0]
+	}
+	for _, size := range []string{"large", "medium", "small"} {
+		if link := e.Cover[size]; link != "" {
+			metadata.ImageURL = link
+			break
+		}
+	}
+
+	return metadata
+}
+
+// ParseSeriesLabel splits a series label such as "Discworld ; 3" into the series name and volume number
+func ParseSeriesLabel(label string) (string, int) {
+	label = strings.TrimSpace(label)
+	match := seriesVolumePattern.FindStringSubmatch(label)
+	if match == nil || strings.TrimSpace(match[1]) == "" {
+		return label, 0
+	}
+	volume, _ := strconv.Atoi(match[2])
+	return strings.TrimSpace(match[1]), volume
+}
+
+func firstN(values []string, n int) []string {
+	if len(values) > n {
+		return values[:n]
+	}
+	return values
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// MetadataProvider is a source of book metadata such as Google Books or Open Library
+type MetadataProvider interface {
+	Name() string
+	ISBN(isbn string) (*BookMetadata, error)
+	Search(query string, limit int) ([]BookMetadata, error)
+}
+
+// volumeProvider is implemented by providers able to resolve a Google Books volume ID
+type volumeProvider interface {
+	Volume(id string) (*BookMetadata, error)
+}
+
+// MetadataService queries providers by priority and fills missing fields from the lower priority ones
+type MetadataService struct {
+	Providers []MetadataProvider
+}
+
+// NewMetadataService returns the default providers: Google Books first, Open Library as fallback
+func NewMetadataService(db *gorm.DB) *MetadataService {
+	return &MetadataService{
+		Providers: []MetadataProvider{
+			NewGoogleBooksClient(db),
+			NewOpenLibraryClient(db),
+		},
+	}
+}
+
+// Volume resolves a Google Books volume and completes it by ISBN from the other providers
+func (s *MetadataService) Volume(id string) (*BookMetadata, error) {
+	var metadata *BookMetadata
+	var lastErr error = ErrMetadataNotFound
+
+	for _, provider := range s.Providers {
+		volumes, ok := provider.(volumeProvider)
+		if !ok {
+			continue
+		}
+		result, err := volumes.Volume(id)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		metadata = result
+		break
+	}
+	if metadata == nil {
+		return nil, lastErr
+	}
+
+	return s.complete(metadata), nil
+}
+
+// LookupISBN merges the metadata every provider has for an ISBN
+func (s *MetadataService) LookupISBN(isbn string) (*BookMetadata, error) {
+	var metadata *BookMetadata
+	var lastErr error = ErrMetadataNotFound
+
+	for _, provider := range s.Providers {
+		if metadata != nil && metadata.IsComplete() {
+			break
+		}
+		result, err := provider.ISBN(isbn)
+		if err != nil {
+			if !errors.Is(err, ErrMetadataNotFound) {
+				sugar.Warnw("Metadata provider failed", "provider", provider.Name(), "isbn", isbn, "error", err)
+			}
+			lastErr = err
+			continue
+		}
+		metadata = MergeMetadata(metadata, result)
+	}
+
+	if metadata == nil {
+		return nil, lastErr
+	}
+	return metadata, nil
+}
+
+// Search queries every provider and merges the volumes sharing an ISBN, results of the first provider first
+func (s *MetadataService) Search(query string, limit int) ([]BookMetadata, error) {
+	if limit <= 0 || limit > 40 {
+		limit = 10
+	}
+	var results []BookMetadata
+	byISBN := make(map[string]int)
+	var lastErr error
+	answered := false
+
+	for _, provider := range s.Providers {
+		found, err := provider.Search(query, limit)
+		if err != nil {
+			sugar.Warnw("Metadata provider failed", "provider", provider.Name(), "query", query, "error", err)
+			lastErr = err
+			continue
+		}
+		answered = true
+
+		for i := range found {
+			index, ok := searchResultIndex(byISBN, found[i])
+			if ok {
+				results[index] = *MergeMetadata(&results[index], &found[i])
+			} else {
+				index = len(results)
+				results = append(results, found[i])
+			}
+			// Les deux ISBN du volume fusionné désignent le même résultat
+			for _, isbn := range []string{results[index].ISBN13, results[index].ISBN10} {
+				if isbn != "" {
+					byISBN[isbn] = index
+				}
+			}
+		}
+	}
+
+	if !answered && lastErr != nil {
+		return nil, lastErr
+	}
+	if results == nil {
+		return []BookMetadata{}, nil
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchResultIndex returns the index of an already merged result with the same ISBN
+func searchResultIndex(byISBN map[string]int, metadata BookMetadata) (int, bool) {
+	for _, isbn := range []string{metadata.ISBN13, metadata.ISBN10} {
+		if isbn == "" {
+			continue
+		}
+		if index, ok := byISBN[isbn]; ok {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// complete fills the gaps of a volume using its ISBN on the other providers
+func (s *MetadataService) complete(metadata *BookMetadata) *BookMetadata {
+	isbn := metadata.ISBN13
+	if isbn == "" {
+		isbn = metadata.ISBN10
+	}
+	if isbn == "" || metadata.IsComplete() {
+		return metadata
+	}
+
+	for _, provider := range s.Providers {
+		if _, ok := provider.(volumeProvider); ok {
+			continue
+		}
+		result, err := provider.ISBN(isbn)
+		if err != nil {
+			continue
+		}
+		metadata = MergeMetadata(metadata, result)
+		if metadata.IsComplete() {
+			break
+		}
+	}
+	return metadata
+}
+
+// IsComplete reports whether the fields other providers are used for are all present,
+// the series name only coming from Open Library
+func (m *BookMetadata) IsComplete() bool {
+	return m.PageCount > 0 && m.ImageURL != "" && len(m.Genres) > 0 && m.SeriesName != ""
+}
+
+// MergeMetadata fills the empty fields of primary with the values of secondary
+func MergeMetadata(primary, secondary *BookMetadata) *BookMetadata {
+	if primary == nil {
+		return secondary
+	}
+	if secondary == nil {
+		return primary
+	}
+
+	merged := *primary
+	if merged.GoogleBooksID == "" {
+		merged.GoogleBooksID = secondary.GoogleBooksID
+	}
+	if merged.Title == "" {
+		merged.Title = secondary.Title
+	}
+	if len(merged.Authors) == 0 {
+		merged.Authors = secondary.Authors
+	}
+	if merged.Description == "" {
+		merged.Description = secondary.Description
+	}
+	if merged.ImageURL == "" {
+		merged.ImageURL = secondary.ImageURL
+	}
+	if merged.PageCount == 0 {
+		merged.PageCount = secondary.PageCount
+	}
+	if len(merged.Genres) == 0 {
+		merged.Genres = secondary.Genres
+	}
+	if merged.PublishedDate == "" {
+		merged.PublishedDate = secondary.PublishedDate
+	}
+	if merged.Publisher == "" {
+		merged.Publisher = secondary.Publisher
+	}
+	if merged.Language == "" {
+		merged.Language = secondary.Language
+	}
+	if merged.ISBN10 == "" {
+		merged.ISBN10 = secondary.ISBN10
+	}
+	if merged.ISBN13 == "" {
+		merged.ISBN13 = secondary.ISBN13
+	}
+	if merged.SeriesName == "" {
+		merged.SeriesName = secondary.SeriesName
+	}
+	if merged.VolumeNumber == 0 {
+		merged.VolumeNumber = secondary.VolumeNumber
+	}
+	return &merged
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const testISBN = "9780261102385"
+
+// newTestServer serves the handler and counts the requests it receives
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestGoogleBooksClient(server *httptest.Server) *GoogleBooksClient {
+	return &GoogleBooksClient{
+		BaseURL: server.URL,
+		HTTP:    server.Client(),
+		Limiter: NewRateLimiter(1000),
+	}
+}
+
+func newTestOpenLibraryClient(server *httptest.Server) *OpenLibraryClient {
+	return &OpenLibraryClient{
+		BaseURL:   server.URL,
+		CoversURL: server.URL,
+		HTTP:      server.Client(),
+		Limiter:   NewRateLimiter(1000),
+	}
+}
+
+func TestGoogleBooksISBN(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/volumes" || r.URL.Query().Get("q") != "isbn:"+testISBN {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"totalItems": 1, "items": [{
+			"id": "abc123",
+			"volumeInfo": {
+				"title": "The Lord of the Rings",
+				"authors": ["J.R.R. Tolkien"],
+				"publisher": "HarperCollins",
+				"publishedDate": "1991",
+				"pageCount": 1216,
+				"categories": ["Fiction"],
+				"language": "en",
+				"industryIdentifiers": [
+					{"type": "ISBN_10", "identifier": "0261102389"},
+					{"type": "ISBN_13", "identifier": "9780261102385"}
+				],
+				"imageLinks": {"thumbnail": "http://books.google.com/thumb", "small": "http://books.google.com/small"},
+				"seriesInfo": {"bookDisplayNumber": "2"}
+			}
+		}]}`))
+	})
+
+	metadata, err := newTestGoogleBooksClient(server).ISBN(testISBN)
+	if err != nil {
+		t.Fatalf("ISBN returned an error: %v", err)
+	}
+	if metadata.GoogleBooksID != "abc123" || metadata.Title != "The Lord of the Rings" || metadata.PageCount != 1216 {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if metadata.ISBN10 != "0261102389" || metadata.ISBN13 != testISBN {
+		t.Errorf("unexpected ISBNs %q %q", metadata.ISBN10, metadata.ISBN13)
+	}
+	if metadata.ImageURL != "https://books.google.com/small" {
+		t.Errorf("expected the largest image in https, got %q", metadata.ImageURL)
+	}
+	if metadata.VolumeNumber != 2 {
+		t.Errorf("expected volume 2, got %d", metadata.VolumeNumber)
+	}
+}
+
+func TestGoogleBooksISBNNotFound(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"totalItems": 0}`))
+	})
+
+	if _, err := newTestGoogleBooksClient(server).ISBN(testISBN); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("expected ErrMetadataNotFound, got %v", err)
+	}
+}
+
+func TestGoogleBooksISBNRateLimited(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := newTestGoogleBooksClient(server).ISBN(testISBN)
+	if err == nil || errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != metadataMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", metadataMaxAttempts, got)
+	}
+}
+
+func TestGoogleBooksISBNRetriesAfterRateLimit(t *testing.T) {
+	var attempts int32
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"totalItems": 1, "items": [{"id": "abc123", "volumeInfo": {"title": "The Hobbit"}}]}`))
+	})
+
+	metadata, err := newTestGoogleBooksClient(server).ISBN(testISBN)
+	if err != nil {
+		t.Fatalf("ISBN returned an error: %v", err)
+	}
+	if metadata.Title != "The Hobbit" {
+		t.Errorf("unexpected title %q", metadata.Title)
+	}
+}
+
+func TestOpenLibraryISBN(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/books":
+			if r.URL.Query().Get("bibkeys") != "ISBN:"+testISBN {
+				t.Errorf("unexpected request %s", r.URL)
+			}
+			w.Write([]byte(`{"ISBN:9780261102385": {
+				"title": "The Two Towers",
+				"authors": [{"name": "J.R.R. Tolkien"}],
+				"publishers": [{"name": "HarperCollins"}],
+				"publish_date": "1991",
+				"number_of_pages": 352,
+				"subjects": [{"name": "Fantasy"}, {"name": "Middle Earth"}],
+				"cover": {"small": "https://covers.openlibrary.org/s.jpg", "large": "https://covers.openlibrary.org/l.jpg"},
+				"identifiers": {"isbn_10": ["0261102389"]}
+			}}`))
+		case "/isbn/" + testISBN + ".json":
+			w.Write([]byte(`{"series": ["The Lord of the Rings ; 2"]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	metadata, err := newTestOpenLibraryClient(server).ISBN(testISBN)
+	if err != nil {
+		t.Fatalf("ISBN returned an error: %v", err)
+	}
+	if metadata.Title != "The Two Towers" || metadata.PageCount != 352 || metadata.Publisher != "HarperCollins" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if len(metadata.Authors) != 1 || metadata.Authors[0] != "J.R.R. Tolkien" || len(metadata.Genres) != 2 {
+		t.Errorf("unexpected authors or genres %v %v", metadata.Authors, metadata.Genres)
+	}
+	if metadata.ISBN10 != "0261102389" || metadata.ISBN13 != testISBN {
+		t.Errorf("unexpected ISBNs %q %q", metadata.ISBN10, metadata.ISBN13)
+	}
+	if metadata.ImageURL != "https://covers.openlibrary.org/l.jpg" {
+		t.Errorf("expected the large cover, got %q", metadata.ImageURL)
+	}
+	if metadata.SeriesName != "The Lord of the Rings" || metadata.VolumeNumber != 2 {
+		t.Errorf("unexpected series %q %d", metadata.SeriesName, metadata.VolumeNumber)
+	}
+}
+
+func TestOpenLibraryISBNWithoutSeries(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/books" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"ISBN:9780261102385": {"title": "The Two Towers"}}`))
+	})
+
+	metadata, err := newTestOpenLibraryClient(server).ISBN(testISBN)
+	if err != nil {
+		t.Fatalf("ISBN returned an error: %v", err)
+	}
+	if metadata.SeriesName != "" || metadata.VolumeNumber != 0 {
+		t.Errorf("expected no series, got %q %d", metadata.SeriesName, metadata.VolumeNumber)
+	}
+}
+
+func TestOpenLibraryISBNNotFound(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	if _, err := newTestOpenLibraryClient(server).ISBN(testISBN); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("expected ErrMetadataNotFound, got %v", err)
+	}
+}
+
+func TestOpenLibraryISBNRateLimited(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := newTestOpenLibraryClient(server).ISBN(testISBN)
+	if err == nil || errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != metadataMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", metadataMaxAttempts, got)
+	}
+}
+
+// fakeProvider answers searches with fixed results
+type fakeProvider struct {
+	name    string
+	results []BookMetadata
+	err     error
+}
+
+func (p fakeProvider) Name() string { return p.name }
+
+func (p fakeProvider) ISBN(isbn string) (*BookMetadata, error) { return nil, ErrMetadataNotFound }
+
+func (p fakeProvider) Search(query string, limit int) ([]BookMetadata, error) {
+	return p.results, p.err
+}
+
+func TestMetadataServiceSearchMergesOnISBN(t *testing.T) {
+	service := &MetadataService{Providers: []MetadataProvider{
+		fakeProvider{name: "google", results: []BookMetadata{
+			{Title: "The Two Towers", ISBN13: testISBN},
+			{Title: "The Hobbit", ISBN10: "0261102214"},
+		}},
+		fakeProvider{name: "openlibrary", results: []BookMetadata{
+			{Title: "Two Towers", ISBN13: testISBN, PageCount: 352, SeriesName: "The Lord of the Rings"},
+			{Title: "Silmarillion", ISBN13: "9780261102736"},
+		}},
+	}}
+
+	results, err := service.Search("tolkien", 10)
+	if err != nil {
+		t.Fatalf("Search returned an error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Title != "The Two Towers" || results[0].PageCount != 352 || results[0].SeriesName != "The Lord of the Rings" {
+		t.Errorf("expected the volumes sharing an ISBN to be merged, got %+v", results[0])
+	}
+	if results[2].Title != "Silmarillion" {
+		t.Errorf("expected the other provider results last, got %q", results[2].Title)
+	}
+}
+
+func TestMetadataServiceSearchSkipsFailingProvider(t *testing.T) {
+	service := &MetadataService{Providers: []MetadataProvider{
+		fakeProvider{name: "google", err: errors.New("unavailable")},
+		fakeProvider{name: "openlibrary", results: []BookMetadata{{Title: "The Hobbit"}}},
+	}}
+
+	results, err := service.Search("hobbit", 10)
+	if err != nil || len(results) != 1 {
+		t.Errorf("expected the Open Library result, got %v %v", results, err)
+	}
+}
+
+// isbnProvider answers ISBN lookups with fixed metadata and counts them
+type isbnProvider struct {
+	fakeProvider
+	metadata *BookMetadata
+	calls    *int
+}
+
+func (p isbnProvider) ISBN(isbn string) (*BookMetadata, error) {
+	*p.calls++
+	return p.metadata, nil
+}
+
+func TestMetadataServiceLookupISBNCompletesSeries(t *testing.T) {
+	googleCalls, openLibraryCalls := 0, 0
+	service := &MetadataService{Providers: []MetadataProvider{
+		isbnProvider{fakeProvider: fakeProvider{name: "google"}, calls: &googleCalls, metadata: &BookMetadata{
+			Title: "The Two Towers", PageCount: 352, ImageURL: "https://example.com/cover.jpg", Genres: []string{"Fiction"}, VolumeNumber: 2,
+		}},
+		isbnProvider{fakeProvider: fakeProvider{name: "openlibrary"}, calls: &openLibraryCalls, metadata: &BookMetadata{
+			Title: "Two Towers", SeriesName: "The Lord of the Rings", VolumeNumber: 2,
+		}},
+	}}
+
+	metadata, err := service.LookupISBN(testISBN)
+	if err != nil {
+		t.Fatalf("LookupISBN returned an error: %v", err)
+	}
+	if openLibraryCalls != 1 {
+		t.Errorf("expected Open Library to be queried for the series, got %d calls", openLibraryCalls)
+	}
+	if metadata.Title != "The Two Towers" || metadata.SeriesName != "The Lord of the Rings" {
+		t.Errorf("unexpected merged metadata %+v", metadata)
+	}
+}
+
+func TestParseSeriesLabel(t *testing.T) {
+	cases := []struct {
+		label  string
+		name   string
+		volume int
+	}{
+		{"Discworld ; 3", "Discworld", 3},
+		{"Harry Potter #1", "Harry Potter", 1},
+		{"Les Misérables, t. 4", "Les Misérables", 4},
+		{"Foundation, vol. 2", "Foundation", 2},
+		{"La Horde du Contrevent, tome 1", "La Horde du Contrevent", 1},
+		{"Dune (Book 2)", "Dune", 2},
+		{"Catch-22", "Catch-22", 0},
+		{"Fahrenheit 451", "Fahrenheit 451", 0},
+		{"Notebook 3", "Notebook 3", 0},
+		{"The Expanse", "The Expanse", 0},
+	}
+	for _, tc := range cases {
+		name, volume := ParseSeriesLabel(tc.label)
+		if name != tc.name || volume != tc.volume {
+			t.Errorf("ParseSeriesLabel(%q) = %q, %d, expected %q, %d", tc.label, name, volume, tc.name, tc.volume)
+		}
+	}
+}
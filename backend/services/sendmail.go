@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"mime"
 	"net/smtp"
 	"time"
 
@@ -148,13 +149,6 @@ func generateSecureToken(length int) (string, error) {
 
 // sendEmail sends the password reset email
 func (s *EmailService) sendEmail(to, name, token string) error {
-	// SMTP server configuration
-	smtpHost := emailConfig.SMTPHost
-	smtpPort := emailConfig.SMTPPort
-	smtpUsername := emailConfig.SMTPUsername
-	smtpPassword := emailConfig.SMTPPassword
-	fromEmail := emailConfig.FromEmail
-
 	// Reset link configuration
 	frontendURL := emailConfig.FrontendURL
 	resetLink := fmt.Sprintf("%s/password/reset?token=%s", frontendURL, token)
@@ -176,13 +170,43 @@ Cordialement,
 L'équipe Bibliothèque
 `, name, resetLink)
 
+	return sendMessage(to, subject, body)
+}
+
+// SendLoanReminderEmail reminds a borrower that a lent book should have been returned
+func (s *EmailService) SendLoanReminderEmail(to, borrowerName, lenderName, bookTitle string, dueAt time.Time) error {
+	subject := fmt.Sprintf("Rappel : « %s » à rendre à %s", bookTitle, lenderName)
+	body := fmt.Sprintf(`Bonjour %s,
+
+%s vous a prêté « %s », qui devait être rendu le %s.
+Pensez à le lui rapporter dès que possible.
+
+Cordialement,
+L'équipe Bibliothèque
+`, borrowerName, lenderName, bookTitle, dueAt.Format("02/01/2006"))
+
+	if err := sendMessage(to, subject, body); err != nil {
+		logger.Errorw("Failed to send loan reminder email", "error", err)
+		return err
+	}
+	logger.Infow("Loan reminder email sent successfully", "email", to)
+	return nil
+}
+
+// sendMessage sends a plain text email through the configured SMTP server
+func sendMessage(to, subject, body string) error {
+	smtpHost := emailConfig.SMTPHost
+	smtpPort := emailConfig.SMTPPort
+	fromEmail := emailConfig.FromEmail
+
 	msg := "From: " + fromEmail + "\r\n" +
 		"To: " + to + "\r\n" +
-		"Subject: " + subject + "\r\n\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" +
 		body
 
 	// Connect to SMTP server and send email
-	auth := smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)
+	auth := smtp.PlainAuth("", emailConfig.SMTPUsername, emailConfig.SMTPPassword, smtpHost)
 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, fromEmail, []string{to}, []byte(msg))
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
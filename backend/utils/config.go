@@ -40,6 +40,23 @@ type Config struct {
 
 	// Authorized emails for registration
 	AuthorizedEmails []string
+
+	// Book metadata providers
+	GoogleBooksURL     string
+	GoogleBooksAPIKey  string
+	OpenLibraryURL     string
+	OpenLibraryCovers  string
+	MetadataCacheHours int // Durée de vie du cache des métadonnées
+	MetadataRateLimit  int // Requêtes par seconde vers les fournisseurs
+
+	// Jours avant la purge définitive d'un livre de la corbeille, 0 = jamais
+	TrashRetentionDays int
+
+	// Jours entre deux rappels d'un prêt en retard, 0 = aucun rappel
+	LoanReminderDays int
+
+	// Heures entre deux rapprochements des stats avec les livres, 0 = jamais
+	StatsReconcileHours int
 }
 
 // Initialize a global SugaredLogger
@@ -89,6 +106,23 @@ func LoadConfig() (*Config, error) {
 
 		// Authorized emails for registration (comma-separated)
 		AuthorizedEmails: getEnvAsStringSlice("AUTHORIZED_EMAILS", []string{"john@example.com"}),
+
+		// Book metadata providers
+		GoogleBooksURL:     getEnv("GOOGLE_BOOKS_URL", "https://www.googleapis.com/books/v1"),
+		GoogleBooksAPIKey:  getEnv("GOOGLE_BOOKS_API_KEY", ""),
+		OpenLibraryURL:     getEnv("OPEN_LIBRARY_URL", "https://openlibrary.org"),
+		OpenLibraryCovers:  getEnv("OPEN_LIBRARY_COVERS_URL", "https://covers.openlibrary.org"),
+		MetadataCacheHours: getEnvAsInt("METADATA_CACHE_HOURS", 168),
+		MetadataRateLimit:  getEnvAsInt("METADATA_RATE_LIMIT", 5),
+
+		// Trash
+		TrashRetentionDays: getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+
+		// Loans
+		LoanReminderDays: getEnvAsInt("LOAN_REMINDER_DAYS", 7),
+
+		// Stats
+		StatsReconcileHours: getEnvAsInt("STATS_RECONCILE_HOURS", 24),
 	}, nil
 }
 
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidISBN est renvoyée pour un ISBN de longueur ou de clé de contrôle invalide
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN strips separators and returns the ISBN-10 and ISBN-13 forms of a valid ISBN.
+// The ISBN-10 form is empty for 979 prefixed ISBN-13 which have no ISBN-10 equivalent.
+func NormalizeISBN(value string) (isbn10 string, isbn13 string, err error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == 'x' || r == 'X':
+			return 'X'
+		case r == '-' || r == ' ':
+			return -1
+		default:
+			return '?'
+		}
+	}, strings.TrimSpace(value))
+
+	switch len(cleaned) {
+	case 10:
+		if !ValidISBN10(cleaned) {
+			return "", "", ErrInvalidISBN
+		}
+		return cleaned, ISBN10To13(cleaned), nil
+	case 13:
+		if !ValidISBN13(cleaned) {
+			return "", "", ErrInvalidISBN
+		}
+		return ISBN13To10(cleaned), cleaned, nil
+	default:
+		return "", "", ErrInvalidISBN
+	}
+}
+
+// ValidISBN10 checks the format and mod 11 checksum of an ISBN-10
+func ValidISBN10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := isbn[i]
+		var digit int
+		switch {
+		case c >= '0' && c <= '9':
+			digit = int(c - '0')
+		case c == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// ValidISBN13 checks the format, prefix and mod 10 checksum of an ISBN-13
+func ValidISBN13(isbn string) bool {
+	if len(isbn) != 13 || !(strings.HasPrefix(isbn, "978") || strings.HasPrefix(isbn, "979")) {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 13; i++ {
+		c := isbn[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// ISBN10To13 converts a valid ISBN-10 into its 978 prefixed ISBN-13
+func ISBN10To13(isbn10 string) string {
+	base := "978" + isbn10[:9]
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(base[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := (10 - sum%10) % 10
+	return base + string(rune('0'+check))
+}
+
+// ISBN13To10 converts a valid 978 prefixed ISBN-13 into an ISBN-10, or returns an empty string
+func ISBN13To10(isbn13 string) string {
+	if !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+
+	base := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(base[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return base + "X"
+	}
+	return base + string(rune('0'+check))
+}
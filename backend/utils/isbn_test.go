@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeISBN(t *testing.T) {
+	cases := []struct {
+		name   string
+		value  string
+		isbn10 string
+		isbn13 string
+	}{
+		{"ISBN-10", "0306406152", "0306406152", "9780306406157"},
+		{"ISBN-13", "9780306406157", "0306406152", "9780306406157"},
+		{"hyphenated ISBN-10", "0-306-40615-2", "0306406152", "9780306406157"},
+		{"hyphenated ISBN-13", "978-0-306-40615-7", "0306406152", "9780306406157"},
+		{"spaced ISBN-13", " 978 0 306 40615 7 ", "0306406152", "9780306406157"},
+		{"X check digit", "080442957X", "080442957X", "9780804429573"},
+		{"lowercase x check digit", "080442957x", "080442957X", "9780804429573"},
+		{"ISBN-13 converted to an X check digit", "9780804429573", "080442957X", "9780804429573"},
+		{"979 prefix without ISBN-10", "979-10-323-0569-0", "", "9791032305690"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			isbn10, isbn13, err := NormalizeISBN(tc.value)
+			if err != nil {
+				t.Fatalf("NormalizeISBN(%q) returned an error: %v", tc.value, err)
+			}
+			if isbn10 != tc.isbn10 || isbn13 != tc.isbn13 {
+				t.Errorf("NormalizeISBN(%q) = %q, %q, expected %q, %q", tc.value, isbn10, isbn13, tc.isbn10, tc.isbn13)
+			}
+		})
+	}
+}
+
+func TestNormalizeISBNInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+	}{
+		{"wrong ISBN-10 checksum", "0306406153"},
+		{"wrong ISBN-13 checksum", "9780306406158"},
+		{"X in a non-final position", "08044295X7"},
+		{"X in an ISBN-13", "978080442957X"},
+		{"unknown ISBN-13 prefix", "9770306406150"},
+		{"letters", "03064O6152"},
+		{"too short", "030640615"},
+		{"too long", "97803064061570"},
+		{"empty", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := NormalizeISBN(tc.value); !errors.Is(err, ErrInvalidISBN) {
+				t.Errorf("NormalizeISBN(%q) returned %v, expected ErrInvalidISBN", tc.value, err)
+			}
+		})
+	}
+}
+
+func TestValidISBN10(t *testing.T) {
+	cases := map[string]bool{
+		"0306406152": true,
+		"080442957X": true,
+		"0306406153": false,
+		"X804429570": false,
+		"030640615":  false,
+	}
+	for isbn, valid := range cases {
+		if got := ValidISBN10(isbn); got != valid {
+			t.Errorf("ValidISBN10(%q) = %v, expected %v", isbn, got, valid)
+		}
+	}
+}
+
+func TestValidISBN13(t *testing.T) {
+	cases := map[string]bool{
+		"9780306406157": true,
+		"9791032305690": true,
+		"9780306406150": false,
+		"9770306406150": false,
+		"978030640615X": false,
+	}
+	for isbn, valid := range cases {
+		if got := ValidISBN13(isbn); got != valid {
+			t.Errorf("ValidISBN13(%q) = %v, expected %v", isbn, got, valid)
+		}
+	}
+}
+
+func TestISBNConversion(t *testing.T) {
+	if got := ISBN10To13("0306406152"); got != "9780306406157" {
+		t.Errorf("ISBN10To13 = %q, expected 9780306406157", got)
+	}
+	if got := ISBN10To13("080442957X"); got != "9780804429573" {
+		t.Errorf("ISBN10To13 = %q, expected 9780804429573", got)
+	}
+	if got := ISBN13To10("9780306406157"); got != "0306406152" {
+		t.Errorf("ISBN13To10 = %q, expected 0306406152", got)
+	}
+	if got := ISBN13To10("9791032305690"); got != "" {
+		t.Errorf("ISBN13To10 of a 979 ISBN = %q, expected none", got)
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Lettres accentuées et leur forme simple, par paires
+var accentPairs = []string{
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a", "å", "a",
+	"ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i", "ì", "i",
+	"ô", "o", "ö", "o", "ó", "o", "ò", "o", "õ", "o", "ø", "o",
+	"û", "u", "ü", "u", "ú", "u", "ù", "u",
+	"ÿ", "y", "ñ", "n", "œ", "oe", "æ", "ae", "ß", "ss",
+}
+
+// Lettres accentuées ramenées à leur forme simple pour comparer les titres
+var accentReplacer = strings.NewReplacer(accentPairs...)
+
+// AccentPairs returns the accented letters and their plain form, in pairs as for strings.NewReplacer
+func AccentPairs() []string {
+	return append([]string(nil), accentPairs...)
+}
+
+// NormalizeTitle lowercases a title, drops accents, punctuation, subtitles and bracketed suffixes
+// such as "(French Edition)" so that the same book is recognized across sources
+func NormalizeTitle(title string) string {
+	title = strings.ToLower(title)
+	if i := strings.IndexAny(title, "([:"); i > 0 {
+		title = title[:i]
+	}
+	title = accentReplacer.Replace(title)
+
+	var builder strings.Builder
+	space := false
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return builder.String()
+}
+
+// TitleKeyword returns the longest word of the normalized title, the first one on a tie,
+// contained in the normalized form of every title that normalizes the same way
+func TitleKeyword(title string) string {
+	keyword := ""
+	for _, word := range strings.Fields(NormalizeTitle(title)) {
+		if len([]rune(word)) > len([]rune(keyword)) {
+			keyword = word
+		}
+	}
+	return keyword
+}
+
+// NormalizeAuthor returns the normalized last name of an author, written "First Last" or "Last, First"
+func NormalizeAuthor(author string) string {
+	author = strings.TrimSpace(author)
+	if i := strings.Index(author, ","); i > 0 {
+		author = author[:i]
+	} else if fields := strings.Fields(author); len(fields) > 0 {
+		author = fields[len(fields)-1]
+	}
+	return NormalizeTitle(author)
+}
+
+// NormalizeAuthorName returns the normalized full name of an author, "Last, First" becoming "first last",
+// to group the books of an author written differently across sources
+func NormalizeAuthorName(author string) string {
+	if i := strings.Index(author, ","); i > 0 {
+		author = author[i+1:] + " " + author[:i]
+	}
+	return NormalizeTitle(author)
+}
+
+// NormalizeGenre turns a Google Books category such as "Fiction / Fantasy / Epic" into a genre:
+// the first subcategory when there is one ("Fantasy"), otherwise the category itself,
+// "General" levels being ignored
+func NormalizeGenre(category string) string {
+	var levels []string
+	for _, level := range strings.Split(category, "/") {
+		level = strings.Join(strings.Fields(level), " ")
+		if level != "" && !strings.EqualFold(level, "general") {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return ""
+	}
+
+	genre := levels[0]
+	if len(levels) > 1 {
+		genre = levels[1]
+	}
+	runes := []rune(genre)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// NormalizeGenres normalizes the categories of a book and drops the genres appearing twice
+func NormalizeGenres(categories []string) []string {
+	genres := make([]string, 0, len(categories))
+	seen := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		genre := NormalizeGenre(category)
+		key := NormalizeTitle(genre)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		genres = append(genres, genre)
+	}
+	return genres
+}
+
+// Similarity returns a score between 0 and 1 based on the Levenshtein distance of two strings
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
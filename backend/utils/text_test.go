@@ -0,0 +1,18 @@
+package utils
+
+import "testing"
+
+func TestTitleKeyword(t *testing.T) {
+	cases := map[string]string{
+		"The Lord of the Rings":           "rings",
+		"L'Étranger (French Edition)":     "etranger",
+		"Harry Potter: The Philosopher's": "potter",
+		"Le Cœur cousu":                   "coeur",
+		"!!!":                             "",
+	}
+	for title, keyword := range cases {
+		if got := TitleKeyword(title); got != keyword {
+			t.Errorf("TitleKeyword(%q) = %q, expected %q", title, got, keyword)
+		}
+	}
+}
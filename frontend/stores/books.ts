@@ -70,7 +70,7 @@ export const useBooksStore = defineStore('books', {
 
             try {
                 const config = useRuntimeConfig();
-                book.rating = book.rating ? parseInt(String(book.rating)) : 0;
+                book.rating = book.rating ? parseFloat(String(book.rating)) : null;
                 fetch(config.public.BACKEND_URL + '/api/books/' + book.id, {
                     method: 'PUT',
                     headers: {
@@ -1,5 +1,13 @@
 import { defineStore } from 'pinia';
 
+interface TimelinePoint {
+    period: string;
+    booksFinished: number;
+    pagesRead: number;
+    minutesRead: number;
+    averageRating: number | null;
+}
+
 interface StatsState {
     totalBooks: number;
     completedBooks: number;
@@ -8,6 +16,7 @@ interface StatsState {
     favoriteBooks: number;
     totalPages: number;
     averageRating: number;
+    timeline: TimelinePoint[];
 }
 
 export const useStatsStore = defineStore('stats', {
@@ -18,7 +27,8 @@ export const useStatsStore = defineStore('stats', {
         readingBooks: 0,
         favoriteBooks: 0,
         totalPages: 0,
-        averageRating: 0
+        averageRating: 0,
+        timeline: []
     }),
 
     actions: {
@@ -48,6 +58,28 @@ export const useStatsStore = defineStore('stats', {
             }
         },
 
+        async fetchTimeline(period = 'month', from?: string, to?: string) {
+            try {
+                const config = useRuntimeConfig();
+                const params = new URLSearchParams({ period });
+                if (from) params.set('from', from);
+                if (to) params.set('to', to);
+                const response = await fetch(`${config.public.BACKEND_URL}/api/stats/timeline?${params}`, {
+                    method: 'GET',
+                    headers: {
+                        'Content-Type': 'application/json',
+                        'Authorization': 'Bearer ' + localStorage.getItem('jwt')
+                    },
+                    credentials: 'include'
+                });
+
+                const timelineData = await response.json();
+                this.timeline = timelineData.points ?? [];
+            } catch (error) {
+                console.error('Error while fetching stats timeline', error);
+            }
+        },
+
         clearStats() {
             this.totalBooks = 0;
             this.completedBooks = 0;
@@ -56,6 +88,7 @@ export const useStatsStore = defineStore('stats', {
             this.favoriteBooks = 0;
             this.totalPages = 0;
             this.averageRating = 0;
+            this.timeline = [];
         }
     },
 
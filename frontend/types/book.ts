@@ -1,4 +1,6 @@
-export type BookStatus = 'reading' | 'finished' | 'to-read';
+export type BookStatus = 'reading' | 'finished' | 'to-read' | 'abandoned';
+export type BookFormat = 'paperback' | 'hardcover' | 'ebook' | 'audiobook';
+export type ProgressUnit = 'pages' | 'percent' | 'minutes';
 
 export interface Book {
   id: string;
@@ -18,6 +20,15 @@ export interface Book {
   description?: string;
   pageCount?: number;
   publishedDate?: string;
+  seriesId?: string | null;
+  seriesName?: string;
+  volumeNumber?: number;
+  format?: BookFormat;
+  publisher?: string;
+  language?: string;
+  durationMinutes?: number;
+  narrator?: string;
+  progressUnit?: ProgressUnit;
 }
 
 